@@ -0,0 +1,1181 @@
+/**
+ * Assembler
+ * This file contains the entire assembler, minus instruction declarations and forming (emu/instructions.go)
+ * Compared to MiSaSiM, the assembler gives more detailed errors and is strict, meaning that it treats all
+ * warnings as errors (for example, immediate value overflows).
+ *
+ * Also, compared to MiSaSiM, the assembler generates machine code that **should** be to MIPS 1.0 spec
+ */
+
+//Package asm assembles MIPS source into memory images for the emulator
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+//AssemblySettings places the text and data segments in memory
+type AssemblySettings struct {
+	TextStart  uint32  //must be a multiple of 4
+	DataStart  uint32  //must be a multiple of 4
+	KTextStart uint32  //where the .ktext segment (the exception handler) is placed, must be a multiple of 4
+	FileName   string  //only used to fill in Diagnostic.File
+	Pseudo     bool    //accepts the pseudo-instructions in pseudo.go. Off by default, as course vetting is strict
+	ISA        emu.ISA //accepts the MIPS32r2 instructions in mips32r2.go when emu.MIPS32R2, MIPS1 by default
+}
+
+//InputLine is a line of the source, used to map assembled instructions back to the source
+type InputLine struct {
+	Contents   string
+	LineNumber int
+}
+
+const (
+	assemExtractNone int = iota
+	assemExtractData
+	assemExtractText
+	assemExtractKText
+)
+
+func insertMemoryValue(addr, value uint32, mem *emu.MemoryImage) {
+	//assuming value has already been masked
+
+	for addr >= mem.StartingAddr+uint32(len(mem.Memory))*4 {
+		//expanding memory, assuming memory is contiguous for the assembler
+		mem.Memory = append(mem.Memory, 0)
+	}
+
+	//inserting the value
+	prev := mem.Memory[(addr-mem.StartingAddr)/4]
+	prev = prev | (value << ((addr % 4) * 8))
+	mem.Memory[(addr-mem.StartingAddr)/4] = prev
+}
+
+func getLiteralValueFull(s string, labels map[string]uint32, isSignedImm bool) (uint32, error) {
+	//literals in this case can be labels as well
+	s = strings.Trim(s, " \t")
+
+	if s == "" {
+		return 0, fmt.Errorf("expected a literal, got nothing")
+	}
+	if s[0] == '-' || unicode.IsDigit(rune(s[0])) {
+		//is a number
+
+		s = strings.ToLower(s)
+		if strings.Index(s, "0x") == 0 {
+			//the number is hex
+			s = s[2:]
+			var ret uint32 = 0
+			for i := range s {
+				c := s[i]
+				if c <= '9' && c >= '0' {
+					ret = (ret << 4) | uint32(c-'0')
+				} else if c <= 'f' && c >= 'a' {
+					ret = (ret << 4) | (uint32(c-'a') + 10)
+				} else {
+					//invalid character in a hex number
+					return 0, fmt.Errorf("\"%s\" is not a valid hexadecimal number", s)
+				}
+			}
+			return ret, nil
+		}
+
+		//the number is base 10
+		if s[0] == '-' {
+			//the number is negative
+			s = s[1:]
+			var ret uint32
+			for i := range s {
+				c := s[i]
+				if c <= '9' && c >= '0' {
+					if ret > 0xE666666 {
+						//an overflow will occur
+						return 0, fmt.Errorf("\"-%s\" has magnitude too large to store in 32 bits", s)
+					}
+					ret = (ret * 10) + uint32(c-'0')
+				} else {
+					//invalid character in the number
+					return 0, fmt.Errorf("\"-%s\" is not a valid integer number", s)
+				}
+			}
+
+			return ret ^ 0xFFFFFFFF + 1, nil
+		}
+
+		var ret uint32
+		for i := range s {
+			c := s[i]
+			if c <= '9' && c >= '0' {
+				if ret > 0x19999999 {
+					//an overflow will occur
+					return 0, fmt.Errorf("\"%s\" has magnitude too large to store in 32 bits", s)
+				}
+				ret = (ret * 10) + uint32(c-'0')
+			} else {
+				//invalid character in the number
+				return 0, fmt.Errorf("\"%s\" is not a valid integer number", s)
+			}
+		}
+
+		return ret, nil
+	} else {
+		//must be a label
+		v, ok := labels[s]
+		if !ok {
+			return 0, fmt.Errorf("unresolved label \"%s\"", s)
+		}
+		if isSignedImm {
+			//return 0, fmt.Errorf("should not use labels in sign-extending immediate values")
+		}
+		return v, nil
+	}
+}
+
+//LiteralValue parses a decimal, hexadecimal, character or label literal
+func LiteralValue(s string, labels map[string]uint32) (uint32, error) {
+	return getLiteralValueFull(s, labels, false)
+}
+
+func (a *assembler) assembleData(lines []InputLine) (*emu.MemoryImage, map[string]uint32) {
+	//the map returned is a map of generated labels and their memory address
+
+	//data types are as follows:
+	// * .byte 		: one byte
+	// * .halfword 	: two bytes
+	// * .word 		: four bytes
+	// * .ascii		: the characters of a string
+	// * .asciiz	: the characters of a string followed by a null terminator
+	// * .space		: a specified number of bytes
+	// * .alloc		: a specified number of words
+
+	//general format: LabelName: .dataType value
+
+	retMem := new(emu.MemoryImage)
+	labels := make(map[string]uint32)
+
+	currentAddr := a.settings.DataStart - 1
+	retMem.StartingAddr = a.settings.DataStart
+
+	for _, l := range lines {
+		line := l.Contents
+
+		//first removing comments from the line
+		line = strings.Trim(stripDataComment(line), " \t")
+
+		//ignoring empty lines
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			//invalid syntax, should have at least three terms
+			a.reportError(l, ESyntax, "", "data allocations must have at least 3 terms, expected "+
+				"\"LabelName: .dataType value\". Got: \"%s\"", line)
+			continue
+		}
+
+		if fields[0][len(fields[0])-1] != ':' {
+			//no colon following a label declaration
+			a.reportError(l, ESyntax, fields[0], "data allocation labels need to be followed by a colon. Expected "+
+				"\"LabelName: .dataType value\". Got: \"%s\"", line)
+		}
+
+		fields[0] = strings.Trim(fields[0], ": \t")
+
+		switch strings.ToLower(fields[1]) {
+		case ".byte":
+			//merging all other fields together to prepare for comma delimited list
+			dataMerged := strings.Join(fields[2:], "")
+			dataMerged = strings.ReplaceAll(dataMerged, " ", "")
+			values := strings.Split(dataMerged, ",")
+
+			labels[fields[0]] = currentAddr + 1
+			for _, literal := range values {
+				v, e := LiteralValue(literal, labels)
+				if e != nil {
+					a.reportLiteralError(l, literal, e) //no need to skip the rest of the lines
+				}
+
+				currentAddr++
+				if v&0xFFFFFF00 != 0xFFFFFF00 && v&0xFFFFFF00 != 0x0 {
+					//overflow
+					a.reportError(l, EValueOverflow, literal, "\"%s\" overflows a byte", literal)
+				}
+				insertMemoryValue(currentAddr, v&0xFF, retMem)
+			}
+			break
+		case ".halfword":
+			//merging all other fields together to prepare for comma delimited list
+			dataMerged := strings.Join(fields[2:], "")
+			dataMerged = strings.ReplaceAll(dataMerged, " ", "")
+			values := strings.Split(dataMerged, ",")
+
+			labels[fields[0]] = (currentAddr + 2) & 0xFFFFFFFE //accounts for byte alignment
+			for _, literal := range values {
+				v, e := LiteralValue(literal, labels)
+				if e != nil {
+					a.reportLiteralError(l, literal, e) //no need to skip the rest of the lines
+				}
+
+				currentAddr = (currentAddr + 2) & 0xFFFFFFFE
+				if v&0xFFFF0000 != 0xFFFF0000 && v&0xFFFF0000 != 0x0 {
+					//overflow
+					a.reportError(l, EValueOverflow, literal, "\"%s\" overflows a half word", literal)
+				}
+				insertMemoryValue(currentAddr, v&0xFFFF, retMem)
+				currentAddr++ //the last byte of the half word
+			}
+
+			break
+		case ".word":
+			//merging all other fields together to prepare for comma delimited list
+			dataMerged := strings.Join(fields[2:], "")
+			dataMerged = strings.ReplaceAll(dataMerged, " ", "")
+			values := strings.Split(dataMerged, ",")
+
+			labels[fields[0]] = (currentAddr + 4) & 0xFFFFFFFC //accounts for byte alignment
+			for _, literal := range values {
+				v, e := LiteralValue(literal, labels)
+				if e != nil {
+					a.reportLiteralError(l, literal, e) //no need to skip the rest of the lines
+				}
+
+				currentAddr = (currentAddr + 4) & 0xFFFFFFFC
+				insertMemoryValue(currentAddr, v, retMem)
+				currentAddr += 3 //the last byte of the word
+			}
+
+			break
+		case ".float":
+			//merging all other fields together to prepare for comma delimited list
+			dataMerged := strings.Join(fields[2:], "")
+			values := strings.Split(dataMerged, ",")
+
+			labels[fields[0]] = (currentAddr + 4) & 0xFFFFFFFC //accounts for byte alignment
+			for _, literal := range values {
+				v := a.floatLiteral(literal, l, false)
+
+				currentAddr = (currentAddr + 4) & 0xFFFFFFFC
+				insertMemoryValue(currentAddr, uint32(v), retMem)
+				currentAddr += 3 //the last byte of the float
+			}
+
+			break
+		case ".double":
+			//merging all other fields together to prepare for comma delimited list
+			dataMerged := strings.Join(fields[2:], "")
+			values := strings.Split(dataMerged, ",")
+
+			labels[fields[0]] = (currentAddr + 8) & 0xFFFFFFF8 //doubles are aligned to 8 bytes for ldc1
+			for _, literal := range values {
+				v := a.floatLiteral(literal, l, true)
+
+				currentAddr = (currentAddr + 8) & 0xFFFFFFF8
+				insertMemoryValue(currentAddr, uint32(v), retMem) //the low word first, as the machine is little endian
+				insertMemoryValue(currentAddr+4, uint32(v>>32), retMem)
+				currentAddr += 7 //the last byte of the double
+			}
+
+			break
+		case ".ascii", ".asciiz":
+			//the string is everything after the data type, as it may contain spaces and commas
+			literal := strings.Trim(line[strings.Index(line, fields[1])+len(fields[1]):], " \t")
+			str, e := stringLiteral(literal)
+			if e != nil {
+				a.reportError(l, EInvalidLiteral, literal, "invalid string: %s", e.Error())
+			}
+
+			labels[fields[0]] = currentAddr + 1
+			if strings.ToLower(fields[1]) == ".asciiz" {
+				str += "\x00"
+			}
+			for i := 0; len(str) > i; i++ {
+				currentAddr++
+				insertMemoryValue(currentAddr, uint32(str[i]), retMem)
+			}
+
+			break
+		case ".space":
+			currentAddr++
+			labels[fields[0]] = currentAddr
+
+			v, e := LiteralValue(fields[2], labels)
+			if e != nil {
+				a.reportLiteralError(l, fields[2], e)
+				break
+			}
+			if v >= 65536*4 {
+				a.reportError(l, EAllocationTooLarge, fields[2], "allocations larger than 256KiB are prohibited")
+				break
+			}
+
+			for endAddr := currentAddr + v; endAddr > currentAddr; currentAddr++ {
+				insertMemoryValue(currentAddr, 0, retMem)
+			}
+
+			currentAddr -= 1 //a lazy way of accounting for the one extra time it increments currentAddr
+
+			break
+		case ".alloc":
+			currentAddr = (currentAddr + 4) & 0xFFFFFFFC //accounts for byte alignment
+			labels[fields[0]] = currentAddr
+
+			v, e := LiteralValue(fields[2], labels)
+			if e != nil {
+				a.reportLiteralError(l, fields[2], e)
+				break
+			}
+			if v >= 65536 {
+				a.reportError(l, EAllocationTooLarge, fields[2], "allocations larger than 256KiB are prohibited")
+				break
+			}
+
+			for endAddr := currentAddr + v*4; endAddr > currentAddr; currentAddr += 4 {
+				insertMemoryValue(currentAddr, 0, retMem)
+			}
+
+			currentAddr -= 1 //the last byte of the allocation
+
+			break
+		default:
+			a.reportError(l, EInvalidDataType, fields[1], "invalid data type. Valid data types are"+
+				" .byte, .halfword, .word, .float, .double, .ascii, .asciiz, .space, and .alloc")
+			labels[fields[0]] = currentAddr //does this to prevent future errors in text assembly
+		}
+	}
+
+	return retMem, labels
+}
+
+//removes the comment of a line of data, a # within a string is not a comment
+func stripDataComment(line string) string {
+	inString := false
+	for i := 0; len(line) > i; i++ {
+		if inString && line[i] == '\\' {
+			i++ //skipping the escaped character
+		} else if line[i] == '"' {
+			inString = !inString
+		} else if line[i] == '#' && !inString {
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+//parses a double quoted string with the escape sequences \n, \t, \r, \0, \\ and \"
+func stringLiteral(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("strings must be enclosed in double quotes")
+	}
+
+	var sb strings.Builder
+	s = s[1 : len(s)-1]
+	for i := 0; len(s) > i; i++ {
+		if s[i] == '"' {
+			return "", fmt.Errorf("quotes within strings must be escaped as \\\"")
+		}
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("the string ends with an incomplete escape sequence")
+		}
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+			break
+		case 't':
+			sb.WriteByte('\t')
+			break
+		case 'r':
+			sb.WriteByte('\r')
+			break
+		case '0':
+			sb.WriteByte(0)
+			break
+		case '\\', '"', '\'':
+			sb.WriteByte(s[i])
+			break
+		default:
+			return "", fmt.Errorf("\\%c is not a supported escape sequence", s[i])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+//the instructions that link the return address (pc + 8) in a register, which the assembler follows with a nop
+var linkInstructions = map[string]bool{
+	"jal":    true,
+	"jalr":   true,
+	"bltzal": true,
+	"bgezal": true,
+}
+
+//a core instruction to assemble, see assembleInstruction
+type coreInstruction struct {
+	opCode string
+	fields []string
+}
+
+//removes the comment and label of a line of text, leaving only the instruction
+func instructionText(line string) string {
+	if strings.Contains(line, "#") {
+		line = line[:strings.Index(line, "#")]
+	}
+	if strings.Contains(line, ":") {
+		line = line[strings.Index(line, ":")+1:]
+	}
+
+	return strings.Trim(line, " \t")
+}
+
+//obtains the op code and the comma separated fields of an instruction
+func splitInstruction(instr string) (string, []string) {
+	spaceFields := strings.Fields(instr)
+	rest := strings.Join(spaceFields[1:], "")
+	return spaceFields[0], strings.Split(rest, ",")
+}
+
+//the number of words the instruction assembles to, which must be known before the labels are
+func (a *assembler) instructionWords(opCode string, fields []string) int {
+	if p, ok := a.pseudoInstruction(opCode); ok {
+		return p.words(fields)
+	}
+
+	if linkInstructions[strings.ToLower(opCode)] {
+		//followed by a nop
+		return 2
+	}
+
+	return 1
+}
+
+func (a *assembler) extractTextLabels(lines []InputLine, labels map[string]uint32, start uint32) map[string]uint32 {
+	currentAddr := start
+
+	for _, l := range lines {
+		noComment := l.Contents
+		if strings.Contains(noComment, "#") {
+			noComment = noComment[:strings.Index(noComment, "#")]
+		}
+		noLabel := instructionText(l.Contents)
+
+		if noLabel == "" && strings.Contains(noComment, ":") {
+			//label on an empty line, not allowed
+			a.reportError(l, EMisplacedLabel, "", "cannot declare labels on lines without assembly operations")
+		}
+
+		if strings.Contains(noComment, ":") {
+			//there is a label
+			labelName := noComment[:strings.Index(noComment, ":")]
+			labelName = strings.Trim(labelName, " \t")
+			_, ok := labels[labelName]
+			if ok {
+				//label already declared, error
+				a.reportError(l, EDuplicateLabel, labelName, "label \"%s\" already declared", labelName)
+			}
+
+			//even if the error is thrown, the label is to the last one because the program will never be run
+			labels[labelName] = currentAddr
+		}
+
+		if noLabel != "" {
+			opCode, fields := splitInstruction(noLabel)
+			currentAddr += 4 * uint32(a.instructionWords(opCode, fields))
+		}
+
+	}
+
+	return labels
+}
+
+//parses a register, either by number ($8, or the legacy t8) or by its O32 name ($t0)
+func (a *assembler) getRegFromString(s string, line InputLine) (int, bool) {
+	if len(s) == 0 {
+		a.reportError(line, EInvalidRegister, "", "missing register, cannot omit registers")
+		return 0, false
+	}
+
+	if s[0] != '$' && s[0] != 't' {
+		a.reportError(line, EInvalidRegister, s, "registers are marked with a preceding '$' or 't'")
+		return 0, false
+	}
+
+	if s[0] == '$' {
+		if v, ok := emu.LookupRegister(s[1:]); ok {
+			return v, true
+		}
+	}
+
+	v, e := strconv.Atoi(s[1:])
+	if e != nil {
+		a.reportError(line, EInvalidRegister, s, "the specified register \"%s\" is not a valid register. Registers are "+
+			"numbered ($8) or named ($t0)", s)
+		return 0, false
+	}
+
+	if v < 0 || v > 31 {
+		a.reportError(line, EInvalidRegister, s, "invalid register. Registers are between $0 and $31")
+		return 0, false
+	}
+
+	return v, true
+}
+
+func (a *assembler) extractRTypeInfo(fields []string, line InputLine, num int) ([3]int, bool) {
+	if len(fields) != num {
+		//invalid format
+		if num == 3 {
+			a.reportError(line, ESyntax, "", "this register-type instruction must have 3 registers in the form \"opcode $1, $2, $3\"")
+		} else if num == 2 {
+			a.reportError(line, ESyntax, "", "this register-type instruction must have 2 registers in the form \"opcode $1, $2\"")
+		} else {
+			a.reportError(line, ESyntax, "", "this register-type instruction must have 1 register in the form \"opcode $1\"")
+		}
+		return [3]int{}, false
+	}
+
+	var ret [3]int
+	for i := 0; num > i; i++ {
+		v, ok := a.getRegFromString(fields[i], line)
+		if !ok {
+			return ret, false
+		}
+
+		ret[i] = v
+	}
+
+	return ret, true
+}
+
+func (a *assembler) extractStandardITypeInfo(fields []string, line InputLine, labels map[string]uint32, maxMask uint32, isSignedImm bool) ([2]int, uint32, bool) {
+	if len(fields) != 3 {
+		//invalid format
+		a.reportError(line, ESyntax, "", "immediate-type instructions must have 2 registers and one immediate"+
+			" in the form \"opcode $1, $2, [value]\"")
+		return [2]int{}, 0, false
+	}
+
+	var ret [2]int
+	for i := 0; 2 > i; i++ {
+		v, ok := a.getRegFromString(fields[i], line)
+		if !ok {
+			return [2]int{}, 0, false
+		}
+
+		ret[i] = v
+	}
+
+	v, e := getLiteralValueFull(fields[2], labels, isSignedImm)
+	if e != nil {
+		a.reportLiteralError(line, fields[2], e)
+		return ret, 0, false
+	}
+	if (v&maxMask) != maxMask && (v&maxMask) != 0x0 {
+		//overflow
+		a.reportError(line, EValueOverflow, fields[2], "immediate value does not fit into 16 bits")
+		return ret, 0, false
+	}
+
+	return ret, v & 0xFFFF, true
+}
+
+//extracts the registers and the word offset of a branch to the target, in the form "opcode $1, $2, [target]" or
+//"opcode $1, [target]" depending on numRegs. The offset is relative to the instruction after the branch
+//(at addr + 4), as in the MIPS spec
+func (a *assembler) extractBranchInfo(fields []string, line InputLine, labels map[string]uint32, addr uint32, numRegs int) ([2]int, uint32, bool) {
+	if len(fields) != numRegs+1 {
+		//invalid format
+		if numRegs == 2 {
+			a.reportError(line, ESyntax, "", "this branch instruction must have 2 registers and one target"+
+				" in the form \"opcode $1, $2, [label]\"")
+		} else if numRegs == 0 {
+			a.reportError(line, ESyntax, "", "this branch instruction must have one target in the form \"opcode [label]\"")
+		} else {
+			a.reportError(line, ESyntax, "", "this branch instruction must have 1 register and one target"+
+				" in the form \"opcode $1, [label]\"")
+		}
+		return [2]int{}, 0, false
+	}
+
+	var ret [2]int
+	for i := 0; numRegs > i; i++ {
+		v, ok := a.getRegFromString(fields[i], line)
+		if !ok {
+			return [2]int{}, 0, false
+		}
+
+		ret[i] = v
+	}
+
+	targetField := fields[numRegs]
+	target, e := LiteralValue(targetField, labels)
+	if e != nil {
+		a.reportLiteralError(line, targetField, e)
+		return ret, 0, false
+	}
+	if target%4 != 0 {
+		a.reportError(line, EInvalidTarget, targetField, "branch target 0x%X is not word aligned", target)
+		return ret, 0, false
+	}
+
+	offset := (int64(target) - int64(addr) - 4) / 4
+	if offset < -32768 || offset > 32767 {
+		a.reportError(line, EInvalidTarget, targetField, "branch target 0x%X is out of range, branches can only reach "+
+			"128KiB before or after the branch. Use a jump instead", target)
+		return ret, 0, false
+	}
+
+	return ret, uint32(offset) & 0xFFFF, true
+}
+
+//extracts the target of a jump in the form "opcode [target]", returning the 26-bit word address
+//the target must be in the same 256MB region as the instruction after the jump (at addr + 4), as in the MIPS spec
+func (a *assembler) extractJumpTarget(fields []string, line InputLine, labels map[string]uint32, addr uint32) (uint32, bool) {
+	target, e := LiteralValue(fields[0], labels)
+	if e != nil {
+		a.reportLiteralError(line, fields[0], e)
+		return 0, false
+	}
+	if target%4 != 0 {
+		a.reportError(line, EInvalidTarget, fields[0], "jump target 0x%X is not word aligned", target)
+		return 0, false
+	}
+	if target&0xF0000000 != (addr+4)&0xF0000000 {
+		a.reportError(line, EInvalidTarget, fields[0], "jump target 0x%X is out of range, jumps can only reach "+
+			"the 256MB region they are in. Use jr instead", target)
+		return 0, false
+	}
+
+	return (target >> 2) & 0x03FFFFFF, true
+}
+
+func (a *assembler) extractSpecialITypeInfo(fields []string, line InputLine, labels map[string]uint32) ([2]int, uint32, bool) {
+	//form is opcode $1, literal($2)
+
+	if len(fields) != 2 {
+		a.reportError(line, ESyntax, "", "invalid format. This instruction requires the format \"opcode $1, literal($2)\"")
+		return [2]int{}, 0, false
+	}
+
+	var ret [2]int
+
+	v, ok := a.getRegFromString(fields[0], line)
+	if !ok {
+		return ret, 0, false
+	}
+	ret[0] = v
+
+	base, offset, ok := a.extractAddress(fields[1], line, labels)
+	ret[1] = base
+	return ret, offset, ok
+}
+
+//extracts the base register and the sign-extended offset of an address in the form "literal($2)"
+func (a *assembler) extractAddress(field string, line InputLine, labels map[string]uint32) (int, uint32, bool) {
+	//getting the register in the parenthesis
+	if !strings.Contains(field, "(") || !strings.Contains(field, ")") {
+		a.reportError(line, ESyntax, field, "invalid format, missing parenthesis-wrapped register."+
+			" This instruction requires the format \"opcode $1, literal($2)\"")
+		return 0, 0, false
+	}
+
+	base, ok := a.getRegFromString(field[strings.Index(field, "(")+1:strings.Index(field, ")")], line)
+	if !ok {
+		return 0, 0, false
+	}
+
+	//getting literal
+	literal := field[:strings.Index(field, "(")]
+	lv, e := LiteralValue(literal, labels)
+	if e != nil {
+		a.reportLiteralError(line, literal, e)
+		return base, 0, false
+	}
+	if lv < 0xFFFF8000 && lv > 0x7FFF {
+		//overflow, the offset is sign extended
+		a.reportError(line, EValueOverflow, literal, "offset does not fit into 16 bits, it must be between -32768 and 32767")
+		return base, 0, false
+	}
+	return base, lv & 0xFFFF, true
+}
+
+func (a *assembler) extractLUIInfo(fields []string, line InputLine, labels map[string]uint32) (int, uint32, bool) {
+	if len(fields) != 2 {
+		//invalid format
+		a.reportError(line, ESyntax, "", "LUI instructions must have 1 register and one immediate"+
+			" in the form \"lui $1, [value]\"")
+		return 0, 0, false
+	}
+
+	r, ok := a.getRegFromString(fields[0], line)
+	if !ok {
+		return 0, 0, false
+	}
+
+	v, e := LiteralValue(fields[1], labels)
+	if e != nil {
+		a.reportLiteralError(line, fields[1], e)
+		return 0, 0, false
+	}
+	if (v&0xFFFF0000) != 0xFFFF0000 && (v&0xFFFF0000) != 0x0 {
+		//overflow
+		a.reportError(line, EValueOverflow, fields[1], "immediate value does not fit into 16 bits")
+		return 0, 0, false
+	}
+
+	return r, v, true
+}
+
+func (a *assembler) assembleText(lines []InputLine, labels map[string]uint32, start uint32, lineRet map[uint32]InputLine) *emu.MemoryImage {
+	currentAddr := start
+	ret := new(emu.MemoryImage)
+	ret.StartingAddr = start
+
+	for _, l := range lines {
+		noLabel := instructionText(l.Contents)
+		if noLabel == "" {
+			continue
+		}
+
+		opCode, fields := splitInstruction(noLabel)
+		if len(fields) == 0 {
+			a.reportError(l, ESyntax, opCode, "opcodes must have at least one parameter; saw none")
+		}
+
+		instructions := []coreInstruction{{opCode: opCode, fields: fields}}
+		if p, ok := a.pseudoInstruction(opCode); ok {
+			//every word of the expansion is mapped back to the pseudo-instruction's line
+			instructions = a.expandPseudo(l, p, fields, labels)
+		}
+
+		for _, instr := range instructions {
+			insertMemoryValue(currentAddr, a.assembleInstruction(l, currentAddr, instr.opCode, instr.fields, labels), ret)
+			lineRet[currentAddr] = l
+			if linkInstructions[strings.ToLower(instr.opCode)] {
+				//adding NOP after the link, which returns to the instruction after it
+				currentAddr += 4
+				insertMemoryValue(currentAddr, 0, ret)
+			}
+
+			currentAddr += 4
+		}
+	}
+
+	return ret
+}
+
+//assembles a single core instruction of the line, which is placed at addr
+func (a *assembler) assembleInstruction(l InputLine, addr uint32, opCode string, fields []string, labels map[string]uint32) uint32 {
+	if mips32r2Instructions[strings.ToLower(opCode)] {
+		return a.assembleMIPS32R2(l, opCode, fields, labels)
+	}
+	if instruction, ok := a.assembleFPU(l, addr, opCode, fields, labels); ok {
+		return instruction
+	}
+
+	var instruction uint32 = 0
+
+	switch strings.ToLower(opCode) {
+	case "add":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpADD, regs[1], regs[2], regs[0], 0, emu.FnADD)
+		break
+	case "addi":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, true)
+		instruction = emu.FormIInstruction(emu.OpADDI, regs[1], regs[0], imm)
+		break
+	case "addu":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpADDU, regs[1], regs[2], regs[0], 0, emu.FnADDU)
+		break
+	case "addiu":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, true)
+		instruction = emu.FormIInstruction(emu.OpADDIU, regs[1], regs[0], imm)
+		break
+	case "and":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpAND, regs[1], regs[2], regs[0], 0, emu.FnAND)
+		break
+	case "andi":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		instruction = emu.FormIInstruction(emu.OpANDI, regs[1], regs[0], imm)
+		break
+	case "beq":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 2)
+		instruction = emu.FormIInstruction(emu.OpBEQ, regs[0], regs[1], offset)
+		break
+	case "bne":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 2)
+		instruction = emu.FormIInstruction(emu.OpBNE, regs[0], regs[1], offset)
+		break
+	case "div":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpDIV, regs[0], regs[1], 0, 0, emu.FnDIV)
+		break
+	case "divu":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpDIVU, regs[0], regs[1], 0, 0, emu.FnDIVU)
+		break
+	case "jr":
+		regs, _ := a.extractRTypeInfo(fields, l, 1)
+		instruction = emu.FormRInstruction(emu.OpJR, regs[0], 0, 0, 0, emu.FnJR)
+		break
+	case "mfhi":
+		regs, _ := a.extractRTypeInfo(fields, l, 1)
+		instruction = emu.FormRInstruction(emu.OpMFHI, 0, 0, regs[0], 0, emu.FnMFHI)
+		break
+	case "mflo":
+		regs, _ := a.extractRTypeInfo(fields, l, 1)
+		instruction = emu.FormRInstruction(emu.OpMFLO, 0, 0, regs[0], 0, emu.FnMFLO)
+		break
+	case "mult":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpMULT, regs[0], regs[1], 0, 0, emu.FnMULT)
+		break
+	case "multu":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpMULTU, regs[0], regs[1], 0, 0, emu.FnMULTU)
+		break
+	case "xor":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpXOR, regs[1], regs[2], regs[0], 0, emu.FnXOR)
+		break
+	case "or":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpOR, regs[1], regs[2], regs[0], 0, emu.FnOR)
+		break
+	case "ori":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		instruction = emu.FormIInstruction(emu.OpORI, regs[1], regs[0], imm)
+		break
+	case "slt":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSLT, regs[1], regs[2], regs[0], 0, emu.FnSLT)
+		break
+	case "slti":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, true)
+		instruction = emu.FormIInstruction(emu.OpSLTI, regs[1], regs[0], imm)
+		break
+	case "sltiu":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		instruction = emu.FormIInstruction(emu.OpSLTIU, regs[1], regs[0], imm)
+		break
+	case "sltu":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSLTU, regs[1], regs[2], regs[0], 0, emu.FnSLTU)
+		break
+	case "sll":
+		regs, v, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		if v > 31 {
+			//invalid shift amount
+			a.reportError(l, EValueOverflow, fields[2], "cannot shift by more than 31 bits and cannot be a negative number")
+			v = v & 0x1F //just to make it keep going
+		}
+		instruction = emu.FormRInstruction(emu.OpSLL, 0, regs[1], regs[0], int(v), emu.FnSLL)
+		break
+	case "srl":
+		regs, v, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		if v > 31 {
+			//invalid shift amount
+			a.reportError(l, EValueOverflow, fields[2], "cannot shift by more than 31 bits and cannot be a negative number")
+			v = v & 0x1F //just to make it keep going
+		}
+		instruction = emu.FormRInstruction(emu.OpSRL, 0, regs[1], regs[0], int(v), emu.FnSRL)
+		break
+	case "sra":
+		regs, v, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		if v > 31 {
+			//invalid shift amount
+			a.reportError(l, EValueOverflow, fields[2], "cannot shift by more than 31 bits and cannot be a negative number")
+			v = v & 0x1F //just to make it keep going
+		}
+		instruction = emu.FormRInstruction(emu.OpSRA, 0, regs[1], regs[0], int(v), emu.FnSRA)
+		break
+	case "sllv":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSLL, regs[2], regs[1], regs[0], 0, emu.FnSLLV)
+		break
+	case "srlv":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSRL, regs[2], regs[1], regs[0], 0, emu.FnSRLV)
+		break
+	case "srav":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSRA, regs[2], regs[1], regs[0], 0, emu.FnSRAV)
+		break
+	case "sub":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSUB, regs[1], regs[2], regs[0], 0, emu.FnSUB)
+		break
+	case "subu":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSUBU, regs[1], regs[2], regs[0], 0, emu.FnSUBU)
+		break
+	case "lw":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLW, regs[1], regs[0], v)
+		break
+	case "lb":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLB, regs[1], regs[0], v)
+		break
+	case "lbu":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLBU, regs[1], regs[0], v)
+		break
+	case "sw":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpSW, regs[1], regs[0], v)
+		break
+	case "sb":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpSB, regs[1], regs[0], v)
+		break
+	case "j":
+		target, _ := a.extractJumpTarget(fields, l, labels, addr)
+		instruction = emu.FormJInstruction(emu.OpJ, target)
+		break
+	case "jal":
+		target, _ := a.extractJumpTarget(fields, l, labels, addr)
+		instruction = emu.FormJInstruction(emu.OpJAL, target)
+		break
+	case "swi":
+		v, e := LiteralValue(fields[0], labels)
+		if e != nil {
+			a.reportLiteralError(l, fields[0], e)
+		}
+		instruction = emu.FormIInstruction(emu.OpSWI, 0, 0, v)
+		break
+	case "lui":
+		reg, v, _ := a.extractLUIInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLUI, 0, reg, v)
+		break
+	case "nor":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpNOR, regs[1], regs[2], regs[0], 0, emu.FnNOR)
+		break
+	case "jalr":
+		//either "jalr $rs", linking to $31, or "jalr $rd, $rs"
+		if len(fields) == 1 {
+			regs, _ := a.extractRTypeInfo(fields, l, 1)
+			instruction = emu.FormRInstruction(emu.OpJALR, regs[0], 0, 31, 0, emu.FnJALR)
+		} else {
+			regs, _ := a.extractRTypeInfo(fields, l, 2)
+			instruction = emu.FormRInstruction(emu.OpJALR, regs[1], 0, regs[0], 0, emu.FnJALR)
+		}
+		break
+	case "mthi":
+		regs, _ := a.extractRTypeInfo(fields, l, 1)
+		instruction = emu.FormRInstruction(emu.OpMTHI, regs[0], 0, 0, 0, emu.FnMTHI)
+		break
+	case "mtlo":
+		regs, _ := a.extractRTypeInfo(fields, l, 1)
+		instruction = emu.FormRInstruction(emu.OpMTLO, regs[0], 0, 0, 0, emu.FnMTLO)
+		break
+	case "xori":
+		regs, imm, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		instruction = emu.FormIInstruction(emu.OpXORI, regs[1], regs[0], imm)
+		break
+	case "lh":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLH, regs[1], regs[0], v)
+		break
+	case "lhu":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLHU, regs[1], regs[0], v)
+		break
+	case "sh":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpSH, regs[1], regs[0], v)
+		break
+	case "lwl":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLWL, regs[1], regs[0], v)
+		break
+	case "lwr":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpLWR, regs[1], regs[0], v)
+		break
+	case "swl":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpSWL, regs[1], regs[0], v)
+		break
+	case "swr":
+		regs, v, _ := a.extractSpecialITypeInfo(fields, l, labels)
+		instruction = emu.FormIInstruction(emu.OpSWR, regs[1], regs[0], v)
+		break
+	case "blez":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpBLEZ, regs[0], 0, offset)
+		break
+	case "bgtz":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpBGTZ, regs[0], 0, offset)
+		break
+	case "bltz":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpREGIMM, regs[0], emu.RtBLTZ, offset)
+		break
+	case "bgez":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpREGIMM, regs[0], emu.RtBGEZ, offset)
+		break
+	case "bltzal":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpREGIMM, regs[0], emu.RtBLTZAL, offset)
+		break
+	case "bgezal":
+		regs, offset, _ := a.extractBranchInfo(fields, l, labels, addr, 1)
+		instruction = emu.FormIInstruction(emu.OpREGIMM, regs[0], emu.RtBGEZAL, offset)
+		break
+	case "syscall":
+		instruction = emu.FormRInstruction(emu.OpSYSCALL, 0, 0, 0, 0, emu.FnSYSCALL)
+		break
+	case "break":
+		//the optional code is not used by the emulator, but is available to the exception handler
+		var code uint32
+		if fields[0] != "" {
+			v, e := LiteralValue(fields[0], labels)
+			if e != nil {
+				a.reportLiteralError(l, fields[0], e)
+			} else if v > 0xFFFFF {
+				a.reportError(l, EValueOverflow, fields[0], "break codes must fit into 20 bits")
+			} else {
+				code = v
+			}
+		}
+		instruction = emu.FormRInstruction(emu.OpBREAK, 0, 0, 0, 0, emu.FnBREAK) | code<<6
+		break
+	case "mfc0":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpCOP0, emu.RsMFC0, regs[0], regs[1], 0, 0)
+		break
+	case "mtc0":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpCOP0, emu.RsMTC0, regs[0], regs[1], 0, 0)
+		break
+	case "eret":
+		instruction = emu.FormRInstruction(emu.OpCOP0, emu.RsCO, 0, 0, 0, emu.FnERET)
+		break
+	case "nop":
+		instruction = 0
+	default:
+		if _, ok := pseudoInstructions[strings.ToLower(opCode)]; ok {
+			a.reportError(l, EInvalidOpcode, opCode, "\"%s\" is a pseudo-instruction, which are only accepted when "+
+				"pseudo-instructions are enabled", opCode)
+			break
+		}
+		a.reportError(l, EInvalidOpcode, opCode, "invalid opcode \"%s\". Note that this assembler only supports the"+
+			" MIPS core ISA and does not support pseudo-opcodes unless they are enabled", opCode)
+	}
+
+	return instruction
+}
+
+//Assemble assembles the source file into system memory. It also returns the source line of every instruction address,
+//the diagnostics (the program must not be emulated if CountErrors reports any) and the labels.
+//Assemble keeps no state between calls and is safe to call concurrently
+func Assemble(file string, settings AssemblySettings) (emu.SystemMemory, map[uint32]InputLine, []Diagnostic, map[string]uint32) {
+	//input will be newline delimited
+	lines := strings.Split(file, "\n")
+	a := &assembler{
+		settings: settings,
+		source:   lines,
+	}
+
+	var textLines []InputLine
+	var dataLines []InputLine
+	var kTextLines []InputLine
+
+	//extracting the text and data lines from the code
+	mode := assemExtractNone
+	for i, l := range lines {
+
+		//line preconditioning
+		l = strings.Trim(l, " \t\r\n")
+		l = strings.ReplaceAll(l, "\t", " ")
+
+		//assembler directive detection
+		if strings.Index(l, ".data ") == 0 || l == ".data" {
+			mode = assemExtractData
+			l = strings.Replace(l, ".data", "", 1)
+			if l == "" {
+				continue
+			}
+		} else if strings.Index(l, ".text") == 0 || l == ".text" {
+			mode = assemExtractText
+			l = strings.Replace(l, ".text", "", 1)
+			if l == "" {
+				continue
+			}
+		} else if strings.Index(l, ".ktext") == 0 {
+			mode = assemExtractKText
+			l = strings.Replace(l, ".ktext", "", 1)
+			if l == "" {
+				continue
+			}
+		}
+
+		//acting on directives
+		if mode == assemExtractData {
+			dataLines = append(dataLines, InputLine{
+				Contents:   l,
+				LineNumber: i + 1, //lines are 1 indexed
+			})
+		} else if mode == assemExtractText {
+			textLines = append(textLines, InputLine{
+				Contents:   l,
+				LineNumber: i + 1,
+			})
+		} else if mode == assemExtractKText {
+			kTextLines = append(kTextLines, InputLine{
+				Contents:   l,
+				LineNumber: i + 1,
+			})
+		}
+	}
+
+	dataMem, labels := a.assembleData(dataLines)
+	labels = a.extractTextLabels(textLines, labels, a.settings.TextStart)
+	labels = a.extractTextLabels(kTextLines, labels, a.settings.KTextStart)
+	lineRet := make(map[uint32]InputLine)
+	textMem := a.assembleText(textLines, labels, a.settings.TextStart, lineRet)
+	kTextMem := a.assembleText(kTextLines, labels, a.settings.KTextStart, lineRet)
+
+	//checking to ensure the data memory and text memory don't overlap
+	if dataMem.StartingAddr < textMem.StartingAddr && dataMem.StartingAddr+uint32(len(dataMem.Memory)) >= textMem.StartingAddr {
+		//collision
+		a.reportError(InputLine{
+			Contents:   "{overall file}",
+			LineNumber: 0,
+		}, EMemoryOverlap, "", "assembled text and data memory overlaps, change the settings and assemble again")
+		//no need to return now because it will be caught later
+	} else if textMem.StartingAddr < dataMem.StartingAddr && textMem.StartingAddr+uint32(len(textMem.Memory)) >= dataMem.StartingAddr {
+		//collision
+		a.reportError(InputLine{
+			Contents:   "{overall file}",
+			LineNumber: 0,
+		}, EMemoryOverlap, "", "assembled text and data memory overlaps, change the settings and assemble again")
+		//no need to return now because it will be caught later
+	}
+
+	//creating system memory
+	sysMem := make(emu.SystemMemory)
+	sysMem.AddImage(textMem)
+	sysMem.AddImage(dataMem)
+	sysMem.AddImage(kTextMem)
+
+	return sysMem, lineRet, a.diagnostics, labels
+}
@@ -0,0 +1,116 @@
+package asm
+
+import (
+	"testing"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+func TestBranchOffsets(t *testing.T) {
+	src := ".text\n" +
+		"top: beq $t0, $t1, forward\n" +
+		"nop\n" +
+		"forward: bne $t0, $0, top\n" +
+		"self: bgez $t0, self\n" +
+		"bltzal $t0, top"
+	mem, _ := mustAssemble(t, src, AssemblySettings{TextStart: 0x400000, DataStart: 0x10010000})
+
+	checkWords(t, mem, 0x400000, []uint32{
+		emu.FormIInstruction(emu.OpBEQ, 8, 9, 1), //one word after the next instruction
+		0,                                        //nop
+		emu.FormIInstruction(emu.OpBNE, 8, 0, 0xFFFD), //back to top, 3 words before the next instruction
+		emu.FormIInstruction(emu.OpREGIMM, 8, emu.RtBGEZ, 0xFFFF),
+		emu.FormIInstruction(emu.OpREGIMM, 8, emu.RtBLTZAL, 0xFFFB),
+		0, //the nop after the link
+	})
+}
+
+func TestBranchRange(t *testing.T) {
+	const addr = 0x400000
+	cases := []struct {
+		name   string
+		target uint32
+		offset uint32
+		ok     bool
+	}{
+		{"furthest forward", addr + 4 + 32767*4, 0x7FFF, true},
+		{"past forward", addr + 4 + 32768*4, 0, false},
+		{"furthest backward", addr + 4 - 32768*4, 0x8000, true},
+		{"past backward", addr + 4 - 32769*4, 0, false},
+		{"unaligned", addr + 6, 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := &assembler{}
+			labels := map[string]uint32{"target": c.target}
+			regs, offset, ok := a.extractBranchInfo([]string{"$t0", "$t1", "target"}, InputLine{LineNumber: 1}, labels, addr, 2)
+			if ok != c.ok {
+				t.Fatalf("branch from 0x%X to 0x%X: got ok %t, want %t", addr, c.target, ok, c.ok)
+			}
+			if !ok {
+				if CountErrors(a.diagnostics) != 1 || a.diagnostics[0].Code != EInvalidTarget {
+					t.Errorf("the invalid target was not reported: %v", a.diagnostics)
+				}
+				return
+			}
+			if regs != [2]int{8, 9} || offset != c.offset {
+				t.Errorf("got registers %v and offset 0x%X, want [8 9] and 0x%X", regs, offset, c.offset)
+			}
+		})
+	}
+}
+
+func TestJumpRegion(t *testing.T) {
+	cases := []struct {
+		name   string
+		addr   uint32
+		target uint32
+		ok     bool
+	}{
+		{"same region", 0x00400000, 0x0FFFFFFC, true},
+		{"next region", 0x00400000, 0x10000000, false},
+		{"previous region", 0x10000000, 0x0FFFFFFC, false},
+		//the region is the one of the instruction after the jump
+		{"jump at the end of a region", 0x0FFFFFFC, 0x10000004, true},
+		{"jump back from the end of a region", 0x0FFFFFFC, 0x0FFFFFF0, false},
+		{"unaligned", 0x00400000, 0x00400002, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := &assembler{}
+			labels := map[string]uint32{"target": c.target}
+			field, ok := a.extractJumpTarget([]string{"target"}, InputLine{LineNumber: 1}, labels, c.addr)
+			if ok != c.ok {
+				t.Fatalf("jump from 0x%X to 0x%X: got ok %t, want %t", c.addr, c.target, ok, c.ok)
+			}
+			if !ok {
+				if CountErrors(a.diagnostics) != 1 || a.diagnostics[0].Code != EInvalidTarget {
+					t.Errorf("the invalid target was not reported: %v", a.diagnostics)
+				}
+				return
+			}
+			if field != c.target>>2&0x03FFFFFF {
+				t.Errorf("got the target field 0x%X, want 0x%X", field, c.target>>2&0x03FFFFFF)
+			}
+		})
+	}
+}
+
+func TestJumps(t *testing.T) {
+	src := ".text\n" +
+		"top: j end\n" +
+		"jal top\n" +
+		"end: nop"
+	mem, labels := mustAssemble(t, src, AssemblySettings{TextStart: 0x400000, DataStart: 0x10010000})
+
+	if labels["end"] != 0x40000C {
+		t.Fatalf("end is at 0x%X, want 0x40000C as jal is followed by a nop", labels["end"])
+	}
+	checkWords(t, mem, 0x400000, []uint32{
+		emu.FormJInstruction(emu.OpJ, 0x40000C>>2),
+		emu.FormJInstruction(emu.OpJAL, 0x400000>>2),
+		0,
+	})
+}
@@ -0,0 +1,195 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/**
+ * Diagnostics
+ * The assembler never prints. Every problem found in the source is recorded as a diagnostic and returned by Assemble,
+ * so that callers can display them however they like (see Diagnostic.String for the traditional format) or emit
+ * them as JSON. All state of an assembly lives in the assembler struct, so Assemble can be called concurrently.
+ */
+
+//Severity of a diagnostic. The assembler is strict, so everything it currently reports is an error
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+//diagnostic codes, see Diagnostic.Code
+const (
+	ESyntax int = iota
+	EInvalidRegister
+	EInvalidLiteral
+	EUnresolvedLabel
+	EDuplicateLabel
+	EMisplacedLabel
+	EValueOverflow
+	EInvalidDataType
+	EAllocationTooLarge
+	EInvalidOpcode
+	EMemoryOverlap
+	EInvalidTarget
+)
+
+//Diagnostic is a problem found while assembling, located in the source
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`   //1-indexed, 0 if the diagnostic is about the whole file
+	Column   int      `json:"column"` //1-indexed byte offset of the token in the line, 0 if unknown
+	Severity Severity `json:"severity"`
+	Code     int      `json:"code"`
+	Message  string   `json:"message"`
+	Token    string   `json:"token,omitempty"`  //the offending token, blank if the whole line is at fault
+	Source   string   `json:"source,omitempty"` //the contents of the line, for display
+}
+
+//the state of a single assembly
+type assembler struct {
+	settings    AssemblySettings
+	source      []string //the unmodified lines of the file, used to locate tokens
+	diagnostics []Diagnostic
+}
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+//MarshalText lets diagnostics be emitted as JSON with a readable severity
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+//String formats the diagnostic as it was traditionally printed, for example
+//"12 (addi $4, $0, 70000): Error: immediate value does not fit into 16 bits"
+func (d Diagnostic) String() string {
+	source := d.Source
+	if len(source) > 64 {
+		//shortening the line
+		source = source[:64]
+	}
+
+	severity := d.Severity.String()
+	return fmt.Sprintf("%d (%s): %s: %s", d.Line, source, strings.ToUpper(severity[:1])+severity[1:], d.Message)
+}
+
+//DecodeErrorCode returns the name of a diagnostic code
+func DecodeErrorCode(code int) string {
+	switch code {
+	case ESyntax:
+		return "eSyntax"
+	case EInvalidRegister:
+		return "eInvalidRegister"
+	case EInvalidLiteral:
+		return "eInvalidLiteral"
+	case EUnresolvedLabel:
+		return "eUnresolvedLabel"
+	case EDuplicateLabel:
+		return "eDuplicateLabel"
+	case EMisplacedLabel:
+		return "eMisplacedLabel"
+	case EValueOverflow:
+		return "eValueOverflow"
+	case EInvalidDataType:
+		return "eInvalidDataType"
+	case EAllocationTooLarge:
+		return "eAllocationTooLarge"
+	case EInvalidOpcode:
+		return "eInvalidOpcode"
+	case EMemoryOverlap:
+		return "eMemoryOverlap"
+	case EInvalidTarget:
+		return "eInvalidTarget"
+	}
+
+	return "unknown"
+}
+
+//CountErrors returns the number of diagnostics that prevent the program from being emulated
+func CountErrors(diagnostics []Diagnostic) int {
+	n := 0
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			n++
+		}
+	}
+
+	return n
+}
+
+//records an error on the line. The token, if not blank, is located in the line to determine the column
+func (a *assembler) reportError(line InputLine, code int, token, format string, fArgs ...interface{}) {
+	a.report(SeverityError, line, code, token, format, fArgs...)
+}
+
+func (a *assembler) report(severity Severity, line InputLine, code int, token, format string, fArgs ...interface{}) {
+	token = strings.Trim(token, " \t")
+
+	column := 0
+	if line.LineNumber > 0 && line.LineNumber <= len(a.source) {
+		if token == "" {
+			//pointing at the start of the line's contents
+			raw := a.source[line.LineNumber-1]
+			column = len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+		} else if i := indexToken(a.source[line.LineNumber-1], token); i >= 0 {
+			column = i + 1
+		}
+	}
+
+	a.diagnostics = append(a.diagnostics, Diagnostic{
+		File:     a.settings.FileName,
+		Line:     line.LineNumber,
+		Column:   column,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, fArgs...),
+		Token:    token,
+		Source:   line.Contents,
+	})
+}
+
+//returns the index of the first occurrence of the token that is not part of a longer word (so that the "1" of
+//"addi $1, $0, 1" is found at the end of the line), or -1 if there is none
+func indexToken(raw, token string) int {
+	isWord := func(c byte) bool {
+		return c == '_' || c == '$' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)))
+	}
+
+	for offset := 0; offset < len(raw); {
+		i := strings.Index(raw[offset:], token)
+		if i < 0 {
+			return -1
+		}
+
+		start := offset + i
+		end := start + len(token)
+		if (start == 0 || !isWord(raw[start-1]) || !isWord(token[0])) &&
+			(end == len(raw) || !isWord(raw[end]) || !isWord(token[len(token)-1])) {
+			return start
+		}
+		offset = start + 1
+	}
+
+	return -1
+}
+
+//records an error returned from LiteralValue for the literal
+func (a *assembler) reportLiteralError(line InputLine, literal string, e error) {
+	literal = strings.Trim(literal, " \t")
+
+	code := EInvalidLiteral
+	if literal != "" && literal[0] != '-' && !unicode.IsDigit(rune(literal[0])) {
+		code = EUnresolvedLabel
+	}
+
+	a.reportError(line, code, literal, "%s", e.Error())
+}
@@ -0,0 +1,249 @@
+package asm
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Floating-point instructions (CP1)
+ * The floating-point registers are written $f0 to $f31. Double-precision operands are register pairs, so they must be
+ * even-numbered registers. The comparisons and bc1t/bc1f take an optional condition flag (0 to 7) as their first
+ * operand, for example "c.lt.s 2, $f0, $f2", and use flag 0 otherwise.
+ */
+
+type fpuInstruction struct {
+	format     int  //the rs field, the format of the sources
+	fn         int  //the function field
+	operands   int  //3 for "fd, fs, ft" and 2 for "fd, fs"
+	compare    bool //in the form "[cc,] fs, ft"
+	destDouble bool
+	srcDouble  bool
+}
+
+var fpuInstructions = map[string]fpuInstruction{
+	"add.s":     {format: emu.FmtS, fn: emu.FnFADD, operands: 3},
+	"add.d":     {format: emu.FmtD, fn: emu.FnFADD, operands: 3, destDouble: true, srcDouble: true},
+	"sub.s":     {format: emu.FmtS, fn: emu.FnFSUB, operands: 3},
+	"sub.d":     {format: emu.FmtD, fn: emu.FnFSUB, operands: 3, destDouble: true, srcDouble: true},
+	"mul.s":     {format: emu.FmtS, fn: emu.FnFMUL, operands: 3},
+	"mul.d":     {format: emu.FmtD, fn: emu.FnFMUL, operands: 3, destDouble: true, srcDouble: true},
+	"div.s":     {format: emu.FmtS, fn: emu.FnFDIV, operands: 3},
+	"div.d":     {format: emu.FmtD, fn: emu.FnFDIV, operands: 3, destDouble: true, srcDouble: true},
+	"sqrt.s":    {format: emu.FmtS, fn: emu.FnFSQRT, operands: 2},
+	"sqrt.d":    {format: emu.FmtD, fn: emu.FnFSQRT, operands: 2, destDouble: true, srcDouble: true},
+	"abs.s":     {format: emu.FmtS, fn: emu.FnFABS, operands: 2},
+	"abs.d":     {format: emu.FmtD, fn: emu.FnFABS, operands: 2, destDouble: true, srcDouble: true},
+	"mov.s":     {format: emu.FmtS, fn: emu.FnFMOV, operands: 2},
+	"mov.d":     {format: emu.FmtD, fn: emu.FnFMOV, operands: 2, destDouble: true, srcDouble: true},
+	"neg.s":     {format: emu.FmtS, fn: emu.FnFNEG, operands: 2},
+	"neg.d":     {format: emu.FmtD, fn: emu.FnFNEG, operands: 2, destDouble: true, srcDouble: true},
+	"round.w.s": {format: emu.FmtS, fn: emu.FnROUNDW, operands: 2},
+	"round.w.d": {format: emu.FmtD, fn: emu.FnROUNDW, operands: 2, srcDouble: true},
+	"trunc.w.s": {format: emu.FmtS, fn: emu.FnTRUNCW, operands: 2},
+	"trunc.w.d": {format: emu.FmtD, fn: emu.FnTRUNCW, operands: 2, srcDouble: true},
+	"ceil.w.s":  {format: emu.FmtS, fn: emu.FnCEILW, operands: 2},
+	"ceil.w.d":  {format: emu.FmtD, fn: emu.FnCEILW, operands: 2, srcDouble: true},
+	"floor.w.s": {format: emu.FmtS, fn: emu.FnFLOORW, operands: 2},
+	"floor.w.d": {format: emu.FmtD, fn: emu.FnFLOORW, operands: 2, srcDouble: true},
+	"cvt.s.d":   {format: emu.FmtD, fn: emu.FnCVTS, operands: 2, srcDouble: true},
+	"cvt.s.w":   {format: emu.FmtW, fn: emu.FnCVTS, operands: 2},
+	"cvt.d.s":   {format: emu.FmtS, fn: emu.FnCVTD, operands: 2, destDouble: true},
+	"cvt.d.w":   {format: emu.FmtW, fn: emu.FnCVTD, operands: 2, destDouble: true},
+	"cvt.w.s":   {format: emu.FmtS, fn: emu.FnCVTW, operands: 2},
+	"cvt.w.d":   {format: emu.FmtD, fn: emu.FnCVTW, operands: 2, srcDouble: true},
+	"c.eq.s":    {format: emu.FmtS, fn: emu.FnCEQ, compare: true},
+	"c.eq.d":    {format: emu.FmtD, fn: emu.FnCEQ, compare: true, srcDouble: true},
+	"c.lt.s":    {format: emu.FmtS, fn: emu.FnCLT, compare: true},
+	"c.lt.d":    {format: emu.FmtD, fn: emu.FnCLT, compare: true, srcDouble: true},
+	"c.le.s":    {format: emu.FmtS, fn: emu.FnCLE, compare: true},
+	"c.le.d":    {format: emu.FmtD, fn: emu.FnCLE, compare: true, srcDouble: true},
+}
+
+var fpuMemoryOps = map[string]int{
+	"lwc1": emu.OpLWC1,
+	"swc1": emu.OpSWC1,
+	"ldc1": emu.OpLDC1,
+	"sdc1": emu.OpSDC1,
+}
+
+//assembles the floating-point instruction, returns false if the op code is not one
+func (a *assembler) assembleFPU(l InputLine, addr uint32, opCode string, fields []string, labels map[string]uint32) (uint32, bool) {
+	var instruction uint32 = 0
+
+	switch strings.ToLower(opCode) {
+	case "mfc1", "mtc1":
+		if len(fields) != 2 {
+			a.reportError(l, ESyntax, "", "%s must be in the form \"%s $1, $f1\"", opCode, strings.ToLower(opCode))
+			break
+		}
+		rt, ok := a.getRegFromString(fields[0], l)
+		fs, fok := a.getFPRegFromString(fields[1], l)
+		if !ok || !fok {
+			break
+		}
+		rs := emu.RsMFC1
+		if strings.ToLower(opCode) == "mtc1" {
+			rs = emu.RsMTC1
+		}
+		instruction = emu.FormRInstruction(emu.OpCOP1, rs, rt, fs, 0, 0)
+		break
+	case "lwc1", "swc1", "ldc1", "sdc1":
+		if len(fields) != 2 {
+			a.reportError(l, ESyntax, "", "invalid format. This instruction requires the format \"opcode $f1, literal($2)\"")
+			break
+		}
+		ft, ok := a.getFPRegFromString(fields[0], l)
+		if !ok {
+			break
+		}
+		base, offset, ok := a.extractAddress(fields[1], l, labels)
+		if !ok {
+			break
+		}
+		op := fpuMemoryOps[strings.ToLower(opCode)]
+		if op == emu.OpLDC1 || op == emu.OpSDC1 {
+			a.checkDoubleReg(ft, fields[0], l)
+		}
+		instruction = emu.FormIInstruction(op, base, ft, offset)
+		break
+	case "bc1t", "bc1f":
+		cc, fields, ok := a.extractConditionFlag(fields, 2, l, labels)
+		if !ok {
+			break
+		}
+		_, offset, ok := a.extractBranchInfo(fields, l, labels, addr, 0)
+		if !ok {
+			break
+		}
+		rt := cc << 2
+		if strings.ToLower(opCode) == "bc1t" {
+			rt |= 0x1
+		}
+		instruction = emu.FormIInstruction(emu.OpCOP1, emu.RsBC1, rt, offset)
+		break
+	default:
+		f, ok := fpuInstructions[strings.ToLower(opCode)]
+		if !ok {
+			return 0, false
+		}
+		instruction = a.assembleFPUArithmetic(l, f, fields, labels)
+	}
+
+	return instruction, true
+}
+
+func (a *assembler) assembleFPUArithmetic(l InputLine, f fpuInstruction, fields []string, labels map[string]uint32) uint32 {
+	if f.compare {
+		cc, fields, ok := a.extractConditionFlag(fields, 3, l, labels)
+		if !ok {
+			return 0
+		}
+		if len(fields) != 2 {
+			a.reportError(l, ESyntax, "", "comparisons must be in the form \"opcode [cc,] $f1, $f2\"")
+			return 0
+		}
+		fs, ok := a.getFPRegFromString(fields[0], l)
+		ft, tok := a.getFPRegFromString(fields[1], l)
+		if !ok || !tok {
+			return 0
+		}
+		if f.srcDouble {
+			a.checkDoubleReg(fs, fields[0], l)
+			a.checkDoubleReg(ft, fields[1], l)
+		}
+		return emu.FormRInstruction(emu.OpCOP1, f.format, ft, fs, cc<<2, f.fn)
+	}
+
+	if len(fields) != f.operands {
+		if f.operands == 3 {
+			a.reportError(l, ESyntax, "", "this floating-point instruction must be in the form \"opcode $f1, $f2, $f3\"")
+		} else {
+			a.reportError(l, ESyntax, "", "this floating-point instruction must be in the form \"opcode $f1, $f2\"")
+		}
+		return 0
+	}
+
+	var regs [3]int
+	for i := range fields {
+		r, ok := a.getFPRegFromString(fields[i], l)
+		if !ok {
+			return 0
+		}
+		if i == 0 && f.destDouble || i > 0 && f.srcDouble {
+			a.checkDoubleReg(r, fields[i], l)
+		}
+		regs[i] = r
+	}
+
+	return emu.FormRInstruction(emu.OpCOP1, f.format, regs[2], regs[1], regs[0], f.fn)
+}
+
+//extracts the optional condition flag, which is present when there are numFields fields. Returns the remaining fields
+func (a *assembler) extractConditionFlag(fields []string, numFields int, line InputLine, labels map[string]uint32) (int, []string, bool) {
+	if len(fields) != numFields {
+		return 0, fields, true
+	}
+
+	v, e := LiteralValue(fields[0], labels)
+	if e != nil {
+		a.reportLiteralError(line, fields[0], e)
+		return 0, fields, false
+	}
+	if v > 7 {
+		a.reportError(line, EValueOverflow, fields[0], "the condition flag must be between 0 and 7")
+		return 0, fields, false
+	}
+
+	return int(v), fields[1:], true
+}
+
+//parses a floating-point register, $f0 to $f31
+func (a *assembler) getFPRegFromString(s string, line InputLine) (int, bool) {
+	if !strings.HasPrefix(strings.ToLower(s), "$f") {
+		a.reportError(line, EInvalidRegister, s, "expected a floating-point register, which are written $f0 to $f31")
+		return 0, false
+	}
+
+	v, e := strconv.Atoi(s[2:])
+	if e != nil || v < 0 || v > 31 {
+		a.reportError(line, EInvalidRegister, s, "invalid floating-point register. Registers are between $f0 and $f31")
+		return 0, false
+	}
+
+	return v, true
+}
+
+//double-precision values are held in register pairs, named by the even register
+func (a *assembler) checkDoubleReg(reg int, token string, line InputLine) {
+	if reg%2 != 0 {
+		a.reportError(line, EInvalidRegister, token, "double-precision operands must be even-numbered registers")
+	}
+}
+
+//parses a .float or .double literal, returning its bits
+func (a *assembler) floatLiteral(literal string, line InputLine, double bool) uint64 {
+	literal = strings.Trim(literal, " \t")
+
+	bitSize := 32
+	if double {
+		bitSize = 64
+	}
+	v, e := strconv.ParseFloat(literal, bitSize)
+	if e != nil {
+		if ne, ok := e.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			a.reportError(line, EValueOverflow, literal, "\"%s\" is out of the range of a %d-bit float", literal, bitSize)
+		} else {
+			a.reportError(line, EInvalidLiteral, literal, "\"%s\" is not a valid floating-point number", literal)
+		}
+		return 0
+	}
+
+	if double {
+		return math.Float64bits(v)
+	}
+	return uint64(math.Float32bits(float32(v)))
+}
@@ -0,0 +1,155 @@
+package asm
+
+import (
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * MIPS32 Release 2 extensions
+ * Only accepted when AssemblySettings.ISA is emu.MIPS32R2, so that code written for a modern MIPS target can be
+ * vetted without loosening the course dialect. When enabled, the native mul takes precedence over the mul
+ * pseudo-instruction.
+ */
+
+var mips32r2Instructions = map[string]bool{
+	"mul": true, "madd": true, "maddu": true, "msub": true, "msubu": true,
+	"clz": true, "clo": true,
+	"movn": true, "movz": true,
+	"seb": true, "seh": true, "wsbh": true,
+	"ext": true, "ins": true,
+	"rotr": true, "rotrv": true,
+}
+
+func (a *assembler) assembleMIPS32R2(l InputLine, opCode string, fields []string, labels map[string]uint32) uint32 {
+	if a.settings.ISA < emu.MIPS32R2 {
+		if _, ok := pseudoInstructions[strings.ToLower(opCode)]; ok {
+			a.reportError(l, EInvalidOpcode, opCode, "\"%s\" is a MIPS32r2 instruction or a pseudo-instruction, which are "+
+				"only accepted when the mips32r2 ISA or pseudo-instructions are enabled", opCode)
+		} else {
+			a.reportError(l, EInvalidOpcode, opCode, "\"%s\" is a MIPS32r2 instruction, which are only accepted when the "+
+				"mips32r2 ISA is enabled", opCode)
+		}
+		return 0
+	}
+
+	var instruction uint32 = 0
+
+	switch strings.ToLower(opCode) {
+	case "mul":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[1], regs[2], regs[0], 0, emu.FnMUL)
+		break
+	case "madd":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[0], regs[1], 0, 0, emu.FnMADD)
+		break
+	case "maddu":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[0], regs[1], 0, 0, emu.FnMADDU)
+		break
+	case "msub":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[0], regs[1], 0, 0, emu.FnMSUB)
+		break
+	case "msubu":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[0], regs[1], 0, 0, emu.FnMSUBU)
+		break
+	case "clz":
+		//the spec requires rt to be the same as rd
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[1], regs[0], regs[0], 0, emu.FnCLZ)
+		break
+	case "clo":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL2, regs[1], regs[0], regs[0], 0, emu.FnCLO)
+		break
+	case "movn":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpMOVN, regs[1], regs[2], regs[0], 0, emu.FnMOVN)
+		break
+	case "movz":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpMOVZ, regs[1], regs[2], regs[0], 0, emu.FnMOVZ)
+		break
+	case "seb":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL3, 0, regs[1], regs[0], emu.ShSEB, emu.FnBSHFL)
+		break
+	case "seh":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL3, 0, regs[1], regs[0], emu.ShSEH, emu.FnBSHFL)
+		break
+	case "wsbh":
+		regs, _ := a.extractRTypeInfo(fields, l, 2)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL3, 0, regs[1], regs[0], emu.ShWSBH, emu.FnBSHFL)
+		break
+	case "ext":
+		regs, pos, size, _ := a.extractBitFieldInfo(fields, l, labels)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL3, regs[1], regs[0], int(size-1), int(pos), emu.FnEXT)
+		break
+	case "ins":
+		regs, pos, size, _ := a.extractBitFieldInfo(fields, l, labels)
+		instruction = emu.FormRInstruction(emu.OpSPECIAL3, regs[1], regs[0], int(pos+size-1), int(pos), emu.FnINS)
+		break
+	case "rotr":
+		regs, v, _ := a.extractStandardITypeInfo(fields, l, labels, 0xFFFF0000, false)
+		if v > 31 {
+			//invalid rotate amount
+			a.reportError(l, EValueOverflow, fields[2], "cannot rotate by more than 31 bits and cannot be a negative number")
+			v = v & 0x1F //just to make it keep going
+		}
+		instruction = emu.FormRInstruction(emu.OpROTR, 1, regs[1], regs[0], int(v), emu.FnSRL)
+		break
+	case "rotrv":
+		regs, _ := a.extractRTypeInfo(fields, l, 3)
+		instruction = emu.FormRInstruction(emu.OpROTRV, regs[2], regs[1], regs[0], 1, emu.FnSRLV)
+		break
+	}
+
+	return instruction
+}
+
+//extracts the registers, position and size of ext and ins, in the form "opcode $1, $2, [pos], [size]"
+func (a *assembler) extractBitFieldInfo(fields []string, line InputLine, labels map[string]uint32) ([2]int, uint32, uint32, bool) {
+	if len(fields) != 4 {
+		//invalid format
+		a.reportError(line, ESyntax, "", "bit field instructions must have 2 registers, a position and a size"+
+			" in the form \"opcode $1, $2, [pos], [size]\"")
+		return [2]int{}, 0, 1, false
+	}
+
+	var ret [2]int
+	for i := 0; 2 > i; i++ {
+		v, ok := a.getRegFromString(fields[i], line)
+		if !ok {
+			return ret, 0, 1, false
+		}
+
+		ret[i] = v
+	}
+
+	pos, e := LiteralValue(fields[2], labels)
+	if e != nil {
+		a.reportLiteralError(line, fields[2], e)
+		return ret, 0, 1, false
+	}
+	size, e := LiteralValue(fields[3], labels)
+	if e != nil {
+		a.reportLiteralError(line, fields[3], e)
+		return ret, 0, 1, false
+	}
+
+	if pos > 31 {
+		a.reportError(line, EValueOverflow, fields[2], "the position must be between 0 and 31")
+		return ret, 0, 1, false
+	}
+	if size == 0 || size > 32-pos {
+		a.reportError(line, EValueOverflow, fields[3], "the size must be at least 1 and the field must end by bit 31")
+		return ret, 0, 1, false
+	}
+
+	return ret, pos, size, true
+}
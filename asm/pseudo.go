@@ -0,0 +1,174 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Pseudo-instructions
+ * Only accepted when AssemblySettings.Pseudo is set. Each pseudo-instruction is expanded into core instructions
+ * before being assembled, using $1 ($at) as the temporary register as MARS and SPIM do.
+ *
+ * The number of words of an expansion must be known when the text labels are extracted, before the label
+ * addresses are, so it only depends on the operands as written (see pseudoInstruction.words).
+ */
+
+type pseudoInstruction struct {
+	name     string
+	form     string //the expected format, for errors
+	operands int
+	numWords int //0 if it depends on the operands
+}
+
+var pseudoInstructions = map[string]pseudoInstruction{
+	"li":   {name: "li", form: "li $1, [value]", operands: 2},
+	"la":   {name: "la", form: "la $1, [label]", operands: 2, numWords: 2},
+	"move": {name: "move", form: "move $1, $2", operands: 2, numWords: 1},
+	"blt":  {name: "blt", form: "blt $1, $2, [label]", operands: 3, numWords: 2},
+	"bgt":  {name: "bgt", form: "bgt $1, $2, [label]", operands: 3, numWords: 2},
+	"ble":  {name: "ble", form: "ble $1, $2, [label]", operands: 3, numWords: 2},
+	"bge":  {name: "bge", form: "bge $1, $2, [label]", operands: 3, numWords: 2},
+	"b":    {name: "b", form: "b [label]", operands: 1, numWords: 1},
+	"beqz": {name: "beqz", form: "beqz $1, [label]", operands: 2, numWords: 1},
+	"bnez": {name: "bnez", form: "bnez $1, [label]", operands: 2, numWords: 1},
+	"not":  {name: "not", form: "not $1, $2", operands: 2, numWords: 1},
+	"neg":  {name: "neg", form: "neg $1, $2", operands: 2, numWords: 1},
+	"mul":  {name: "mul", form: "mul $1, $2, $3", operands: 3, numWords: 2},
+	"l.s":  {name: "l.s", form: "l.s $f1, literal($2)", operands: 2, numWords: 1},
+	"s.s":  {name: "s.s", form: "s.s $f1, literal($2)", operands: 2, numWords: 1},
+	"l.d":  {name: "l.d", form: "l.d $f2, literal($2)", operands: 2, numWords: 1},
+	"s.d":  {name: "s.d", form: "s.d $f2, literal($2)", operands: 2, numWords: 1},
+}
+
+//returns the pseudo-instruction of the op code, if pseudo-instructions are enabled and it is not a native instruction
+func (a *assembler) pseudoInstruction(opCode string) (pseudoInstruction, bool) {
+	if !a.settings.Pseudo {
+		return pseudoInstruction{}, false
+	}
+	if a.settings.ISA >= emu.MIPS32R2 && mips32r2Instructions[strings.ToLower(opCode)] {
+		//native, such as mul
+		return pseudoInstruction{}, false
+	}
+
+	p, ok := pseudoInstructions[strings.ToLower(opCode)]
+	return p, ok
+}
+
+//the number of words the pseudo-instruction expands to
+func (p pseudoInstruction) words(fields []string) int {
+	if p.numWords != 0 {
+		return p.numWords
+	}
+
+	//li, which only takes one word if the value fits in a single immediate
+	if len(fields) != 2 {
+		return 1 //an error will be reported when expanding
+	}
+	v, e := LiteralValue(fields[1], nil)
+	if e != nil {
+		//a label, whose value is not known yet
+		return 2
+	}
+
+	return len(liExpansion(fields[0], v))
+}
+
+//the core instructions loading v into the register
+func liExpansion(reg string, v uint32) []coreInstruction {
+	if v >= 0xFFFF8000 || v <= 0x7FFF {
+		//fits in a sign-extended immediate
+		return []coreInstruction{ins("addiu", reg, "$0", hex(v))}
+	} else if v <= 0xFFFF {
+		return []coreInstruction{ins("ori", reg, "$0", hex(v))}
+	} else if v&0xFFFF == 0 {
+		return []coreInstruction{ins("lui", reg, hex(v>>16))}
+	}
+
+	return []coreInstruction{ins("lui", reg, hex(v>>16)), ins("ori", reg, reg, hex(v&0xFFFF))}
+}
+
+//expands the pseudo-instruction into core instructions. Exactly p.words(fields) instructions are returned, even if
+//the pseudo-instruction is invalid, so that the addresses of the labels extracted earlier remain correct
+func (a *assembler) expandPseudo(l InputLine, p pseudoInstruction, fields []string, labels map[string]uint32) []coreInstruction {
+	ret := a.expandPseudoOperands(l, p, fields, labels)
+	for len(ret) < p.words(fields) {
+		ret = append(ret, ins("nop"))
+	}
+
+	return ret
+}
+
+func (a *assembler) expandPseudoOperands(l InputLine, p pseudoInstruction, fields []string, labels map[string]uint32) []coreInstruction {
+	if len(fields) != p.operands {
+		a.reportError(l, ESyntax, "", "the %s pseudo-instruction must be in the form \"%s\"", p.name, p.form)
+		return nil
+	}
+
+	switch p.name {
+	case "li":
+		v, e := LiteralValue(fields[1], labels)
+		if e != nil {
+			a.reportLiteralError(l, fields[1], e)
+			return nil
+		}
+		if _, e := LiteralValue(fields[1], nil); e != nil {
+			//a label, always loaded with two words (see words)
+			return []coreInstruction{ins("lui", fields[0], hex(v>>16)), ins("ori", fields[0], fields[0], hex(v&0xFFFF))}
+		}
+		return liExpansion(fields[0], v)
+	case "la":
+		v, e := LiteralValue(fields[1], labels)
+		if e != nil {
+			a.reportLiteralError(l, fields[1], e)
+			return nil
+		}
+		return []coreInstruction{ins("lui", "$1", hex(v>>16)), ins("ori", fields[0], "$1", hex(v&0xFFFF))}
+	case "move":
+		return []coreInstruction{ins("addu", fields[0], "$0", fields[1])}
+	case "blt":
+		return []coreInstruction{ins("slt", "$1", fields[0], fields[1]), ins("bne", "$1", "$0", fields[2])}
+	case "bgt":
+		return []coreInstruction{ins("slt", "$1", fields[1], fields[0]), ins("bne", "$1", "$0", fields[2])}
+	case "ble":
+		return []coreInstruction{ins("slt", "$1", fields[1], fields[0]), ins("beq", "$1", "$0", fields[2])}
+	case "bge":
+		return []coreInstruction{ins("slt", "$1", fields[0], fields[1]), ins("beq", "$1", "$0", fields[2])}
+	case "b":
+		return []coreInstruction{ins("beq", "$0", "$0", fields[0])}
+	case "beqz":
+		return []coreInstruction{ins("beq", fields[0], "$0", fields[1])}
+	case "bnez":
+		return []coreInstruction{ins("bne", fields[0], "$0", fields[1])}
+	case "not":
+		return []coreInstruction{ins("nor", fields[0], fields[1], "$0")}
+	case "neg":
+		return []coreInstruction{ins("sub", fields[0], "$0", fields[1])}
+	case "mul":
+		return []coreInstruction{ins("mult", fields[1], fields[2]), ins("mflo", fields[0])}
+	case "l.s":
+		return []coreInstruction{ins("lwc1", fields[0], fields[1])}
+	case "s.s":
+		return []coreInstruction{ins("swc1", fields[0], fields[1])}
+	case "l.d":
+		return []coreInstruction{ins("ldc1", fields[0], fields[1])}
+	case "s.d":
+		return []coreInstruction{ins("sdc1", fields[0], fields[1])}
+	}
+
+	return nil
+}
+
+func ins(opCode string, fields ...string) coreInstruction {
+	if len(fields) == 0 {
+		fields = []string{""} //as splitInstruction does for instructions without operands
+	}
+
+	return coreInstruction{opCode: opCode, fields: fields}
+}
+
+func hex(v uint32) string {
+	return fmt.Sprintf("0x%X", v)
+}
@@ -0,0 +1,186 @@
+package asm
+
+import (
+	"testing"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+//assembles the source, failing the test on any diagnostic
+func mustAssemble(t *testing.T, src string, settings AssemblySettings) (emu.SystemMemory, map[string]uint32) {
+	t.Helper()
+	mem, _, diagnostics, labels := Assemble(src, settings)
+	if CountErrors(diagnostics) != 0 {
+		for _, d := range diagnostics {
+			t.Log(d.String())
+		}
+		t.Fatalf("failed to assemble %q", src)
+	}
+
+	return mem, labels
+}
+
+//checks the words assembled from addr onwards
+func checkWords(t *testing.T, mem emu.SystemMemory, addr uint32, want []uint32) {
+	t.Helper()
+	for i, w := range want {
+		got, _ := mem.Read(addr + uint32(i)*4)
+		if got != w {
+			t.Errorf("word %d at 0x%X: got 0x%08X (%s), want 0x%08X (%s)", i, addr+uint32(i)*4, got,
+				emu.Disassemble(got, addr+uint32(i)*4), w, emu.Disassemble(w, addr+uint32(i)*4))
+		}
+	}
+}
+
+var pseudoSettings = AssemblySettings{
+	TextStart: 0x0000,
+	DataStart: 0x10010000,
+	Pseudo:    true,
+}
+
+func TestLi(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []uint32
+	}{
+		{"5", []uint32{emu.FormIInstruction(emu.OpADDIU, 0, 8, 5)}},
+		{"-2", []uint32{emu.FormIInstruction(emu.OpADDIU, 0, 8, 0xFFFE)}},
+		{"0x7FFF", []uint32{emu.FormIInstruction(emu.OpADDIU, 0, 8, 0x7FFF)}},
+		{"-32768", []uint32{emu.FormIInstruction(emu.OpADDIU, 0, 8, 0x8000)}},
+		{"0x8000", []uint32{emu.FormIInstruction(emu.OpORI, 0, 8, 0x8000)}},
+		{"0xFFFF", []uint32{emu.FormIInstruction(emu.OpORI, 0, 8, 0xFFFF)}},
+		{"0x10000", []uint32{emu.FormIInstruction(emu.OpLUI, 0, 8, 0x1)}},
+		{"0xFFFF0000", []uint32{emu.FormIInstruction(emu.OpLUI, 0, 8, 0xFFFF)}},
+		{"0x12345678", []uint32{
+			emu.FormIInstruction(emu.OpLUI, 0, 8, 0x1234),
+			emu.FormIInstruction(emu.OpORI, 8, 8, 0x5678),
+		}},
+		{"-32769", []uint32{
+			emu.FormIInstruction(emu.OpLUI, 0, 8, 0xFFFF),
+			emu.FormIInstruction(emu.OpORI, 8, 8, 0x7FFF),
+		}},
+		//a label always takes two words, as its value is not known when the labels are extracted
+		{"after", []uint32{
+			emu.FormIInstruction(emu.OpLUI, 0, 8, 0x0),
+			emu.FormIInstruction(emu.OpORI, 8, 8, 0x8),
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			mem, labels := mustAssemble(t, ".text\nli $t0, "+c.value+"\nafter: nop", pseudoSettings)
+			checkWords(t, mem, 0, c.want)
+			if labels["after"] != uint32(len(c.want))*4 {
+				t.Errorf("the label after li is at 0x%X, want 0x%X", labels["after"], len(c.want)*4)
+			}
+		})
+	}
+}
+
+func TestLa(t *testing.T) {
+	src := ".data\nfirst: .word 1\nsecond: .word 2\n.text\nla $t0, second\nafter: nop"
+	mem, labels := mustAssemble(t, src, pseudoSettings)
+
+	if labels["second"] != 0x10010004 {
+		t.Fatalf("second is at 0x%X, want 0x10010004", labels["second"])
+	}
+	checkWords(t, mem, 0, []uint32{
+		emu.FormIInstruction(emu.OpLUI, 0, 1, 0x1001),
+		emu.FormIInstruction(emu.OpORI, 1, 8, 0x0004),
+	})
+	if labels["after"] != 8 {
+		t.Errorf("the label after la is at 0x%X, want 0x8", labels["after"])
+	}
+}
+
+func TestConditionalBranches(t *testing.T) {
+	//the branch is the second word, so the offset back to top is -2 words
+	cases := []struct {
+		opCode   string
+		rs, rt   int
+		branchOp int
+	}{
+		{"blt", 8, 9, emu.OpBNE},
+		{"bgt", 9, 8, emu.OpBNE},
+		{"ble", 9, 8, emu.OpBEQ},
+		{"bge", 8, 9, emu.OpBEQ},
+	}
+
+	for _, c := range cases {
+		t.Run(c.opCode, func(t *testing.T) {
+			mem, labels := mustAssemble(t, ".text\ntop: "+c.opCode+" $t0, $t1, top\nafter: nop", pseudoSettings)
+			checkWords(t, mem, 0, []uint32{
+				emu.FormRInstruction(emu.OpSLT, c.rs, c.rt, 1, 0, emu.FnSLT),
+				emu.FormIInstruction(c.branchOp, 1, 0, 0xFFFE),
+			})
+			if labels["after"] != 8 {
+				t.Errorf("the label after %s is at 0x%X, want 0x8", c.opCode, labels["after"])
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	src := ".text\nmul $t0, $t1, $t2\nafter: nop"
+
+	//a pseudo-instruction in MIPS I
+	mem, labels := mustAssemble(t, src, pseudoSettings)
+	checkWords(t, mem, 0, []uint32{
+		emu.FormRInstruction(emu.OpMULT, 9, 10, 0, 0, emu.FnMULT),
+		emu.FormRInstruction(emu.OpMFLO, 0, 0, 8, 0, emu.FnMFLO),
+	})
+	if labels["after"] != 8 {
+		t.Errorf("the label after mul is at 0x%X in MIPS I, want 0x8", labels["after"])
+	}
+
+	//native in MIPS32r2, with or without pseudo-instructions
+	for _, pseudo := range []bool{true, false} {
+		settings := pseudoSettings
+		settings.Pseudo = pseudo
+		settings.ISA = emu.MIPS32R2
+		mem, labels = mustAssemble(t, src, settings)
+		checkWords(t, mem, 0, []uint32{emu.FormRInstruction(emu.OpSPECIAL2, 9, 10, 8, 0, emu.FnMUL)})
+		if labels["after"] != 4 {
+			t.Errorf("the label after mul is at 0x%X in MIPS32r2 (pseudo %t), want 0x4", labels["after"], pseudo)
+		}
+	}
+
+	//rejected in MIPS I without pseudo-instructions
+	settings := pseudoSettings
+	settings.Pseudo = false
+	if _, _, diagnostics, _ := Assemble(src, settings); CountErrors(diagnostics) == 0 {
+		t.Error("mul was accepted in MIPS I without pseudo-instructions")
+	}
+}
+
+//the label addresses are extracted with words, and must match the instructions expandPseudo returns
+func TestWordsMatchExpansion(t *testing.T) {
+	a := &assembler{settings: pseudoSettings}
+	labels := map[string]uint32{"label": 0x10010004}
+	sources := []string{
+		"li $t0, 5", "li $t0, 0x8000", "li $t0, 0x10000", "li $t0, 0x12345678", "li $t0, label", "li $t0",
+		"la $t0, label", "la $t0, missing", "move $t0, $t1", "blt $t0, $t1, label", "bgt $t0, $t1, label",
+		"ble $t0, $t1, label", "bge $t0, $t1, label", "bge $t0, label", "b label", "beqz $t0, label",
+		"bnez $t0, label", "not $t0, $t1", "neg $t0, $t1", "mul $t0, $t1, $t2", "l.s $f0, 0($t0)",
+		"s.s $f0, 0($t0)", "l.d $f0, 0($t0)", "s.d $f0, 0($t0)",
+	}
+
+	for _, src := range sources {
+		opCode, fields := splitInstruction(src)
+		p, ok := a.pseudoInstruction(opCode)
+		if !ok {
+			t.Errorf("%s is not a pseudo-instruction", opCode)
+			continue
+		}
+
+		expansion := a.expandPseudo(InputLine{Contents: src, LineNumber: 1}, p, fields, labels)
+		if len(expansion) != p.words(fields) {
+			t.Errorf("%q expands to %d instructions, but words reports %d", src, len(expansion), p.words(fields))
+		}
+	}
+
+	//li $t0, la $t0, missing and bge $t0, label
+	if CountErrors(a.diagnostics) != 3 {
+		t.Errorf("%d errors were reported for the 3 invalid pseudo-instructions", CountErrors(a.diagnostics))
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/vet"
+)
+
+/**
+ * Command line interface
+ * Allows the vetter to be driven from scripts and grading pipelines without going through the wizard.
+ * The output is the same as the wizard's, and the exit status reports the outcome (see the exit constants in main.go).
+ *
+ * Usage:
+ *   mipsvet vet -asm p1.asm -assignment p1 -samples 100000 -etol 5 -no-explorer
+ *   mipsvet vet -asm p1.asm -assignment p1 -seed 1234 -sample 42
+ *   mipsvet help
+ */
+
+func runCommandLine(args []string) int {
+	command := args[0]
+	switch command {
+	case "-h", "-help", "--help":
+		//checked before the flags are passed on to vet, so the full usage is displayed
+		command = "help"
+	}
+	if strings.HasPrefix(command, "-") {
+		//flags without a subcommand default to vet
+		command = "vet"
+	} else {
+		args = args[1:]
+	}
+
+	switch strings.ToLower(command) {
+	case "vet":
+		return vetCommand(args)
+	case "help":
+		displayUsage()
+		return exitOK
+	}
+
+	fmt.Printf("Unknown command \"%s\".\n\n", command)
+	displayUsage()
+	return exitUsage
+}
+
+func displayUsage() {
+	fmt.Println("Usage: mipsvet [command] [flags]")
+	fmt.Println("Running without any arguments launches the wizard.")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  vet   assembles, emulates and (optionally) vets an assembly file")
+	fmt.Println("  help  displays this message")
+	fmt.Println()
+	fmt.Println("Flags for vet:")
+	fs := newVetFlagSet(new(vetOptions))
+	fs.SetOutput(os.Stdout)
+	fs.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Exit status:")
+	fmt.Printf("  %d success, %d failed tests or runtime errors, %d invalid usage, %d EULA not agreed to,\n",
+		exitOK, exitFailed, exitUsage, exitEula)
+	fmt.Printf("  %d assembler errors, %d assembly file could not be read\n", exitAssembly, exitFileAccess)
+}
+
+type vetOptions struct {
+	cfg        runConfig
+	isa        string
+	inputFile  string
+	keysFile   string
+	bitmap     string
+	bitmapBase string
+	pixels     string
+	noExplorer bool
+	agreeEula  bool
+}
+
+func newVetFlagSet(opts *vetOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("vet", flag.ContinueOnError)
+	fs.StringVar(&opts.cfg.asmFile, "asm", "", "assembly file to assemble and emulate (required)")
+	fs.StringVar(&opts.cfg.assignment, "assignment", "", "assignment to vet for, blank for no vetting. Options: "+vet.Describe())
+	fs.IntVar(&opts.cfg.numSamples, "samples", 0, fmt.Sprintf("number of samples to emulate (default %d when vetting, 1 otherwise)", defaultVetCount))
+	fs.Int64Var(&opts.cfg.seed, "seed", 0, "seed of the batch, samples are reproducible from it (default random)")
+	fs.IntVar(&opts.cfg.sample, "sample", -1, "only emulate the sample with this index of the batch, requires -seed")
+	fs.IntVar(&opts.cfg.eTol, "etol", defaultETol, "number of errors to tolerate per sample")
+	fs.IntVar(&opts.cfg.limit, "limit", defaultLimit, "maximum dynamic instruction count per sample")
+	fs.IntVar(&opts.cfg.workers, "workers", 0, "number of samples to emulate concurrently (default one per CPU)")
+	fs.BoolVar(&opts.cfg.pseudo, "pseudo", false, "accept pseudo-instructions such as li, la and move (strict by default)")
+	fs.StringVar(&opts.isa, "isa", "mips1", "instruction set to accept and emulate, 'mips1' or 'mips32r2' for the MIPS32 Release 2 extensions")
+	fs.BoolVar(&opts.cfg.syscalls, "syscalls", true, "perform the SPIM syscall services, otherwise syscall is an exception")
+	fs.StringVar(&opts.inputFile, "input", "", "file read by the input syscalls such as read_int")
+	fs.StringVar(&opts.cfg.input, "input-text", "", "text read by the input syscalls, use \\n to separate lines")
+	fs.BoolVar(&opts.cfg.mmio, "mmio", true, "attach the MARS keyboard and display at 0xFFFF0000")
+	fs.StringVar(&opts.keysFile, "keys", "", "file typed on the MMIO keyboard")
+	fs.StringVar(&opts.cfg.keys, "keys-text", "", "text typed on the MMIO keyboard, use \\n for the enter key")
+	fs.StringVar(&opts.bitmap, "bitmap", "", "attach a bitmap display of [width]x[height] pixels, such as 64x64 (none by default)")
+	fs.StringVar(&opts.bitmapBase, "bitmap-base", "0x10010000", "address of the top left pixel of the bitmap display")
+	fs.StringVar(&opts.pixels, "bitmap-format", "rgb888", "pixel format of the bitmap display: rgb888 (0x00RRGGBB words), rgb565 or gray8")
+	fs.IntVar(&opts.cfg.bitmapScale, "bitmap-scale", 1, "size of the square each pixel is saved as")
+	fs.StringVar(&opts.cfg.bitmapPNG, "bitmap-png", "", "file to save the bitmap display of the last emulation to as a PNG")
+	fs.StringVar(&opts.cfg.bitmapGIF, "bitmap-gif", "", "file to save the frames of the bitmap display of the last emulation to as an animated GIF")
+	fs.IntVar(&opts.cfg.frameInterval, "bitmap-frames", 0, "instructions between the frames of the animation (default 1000 with -bitmap-gif)")
+	fs.BoolVar(&opts.cfg.branches, "branches", false, "display how often every conditional branch was taken, over all samples")
+	fs.BoolVar(&opts.cfg.profile, "profile", false, "count how often every line is executed over all samples, see the explorer's profile command")
+	fs.StringVar(&opts.cfg.profileOut, "profile-out", "", "file to save the execution profile to, as HTML if it ends in .html (implies -profile)")
+	fs.BoolVar(&opts.cfg.calls, "calls", false, "profile the functions called with jal and returned from with jr $ra, over all samples")
+	fs.StringVar(&opts.cfg.callsOut, "calls-out", "", "file to save the call profile to as collapsed stacks for flame graph tools (implies -calls)")
+	fs.BoolVar(&opts.cfg.coverage, "coverage", false, "display which lines and branch directions were never exercised by any sample")
+	fs.StringVar(&opts.cfg.coverageOut, "coverage-out", "", "file to save the coverage report to as HTML (implies -coverage)")
+	fs.IntVar(&opts.cfg.trace, "trace", 0, "keep the last [n] instructions executed by every emulation, see the explorer's trace command")
+	fs.StringVar(&opts.cfg.traceOut, "trace-out", "", "file to save the trace of the last emulation to, as JSON lines if it ends in .jsonl (default 1000 instructions)")
+	fs.BoolVar(&opts.noExplorer, "no-explorer", false, "do not launch the explorer after emulation")
+	fs.BoolVar(&opts.agreeEula, "agree-eula", false, "agree to the EULA without being prompted")
+
+	return fs
+}
+
+func vetCommand(args []string) int {
+	opts := new(vetOptions)
+	fs := newVetFlagSet(opts)
+	fs.SetOutput(os.Stdout)
+
+	if e := fs.Parse(args); e != nil {
+		if e == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Printf("Unexpected argument \"%s\".\n", fs.Arg(0))
+		return exitUsage
+	}
+
+	cfg := opts.cfg
+	cfg.explorer = !opts.noExplorer
+
+	seedSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	if !seedSet {
+		if cfg.sample >= 0 {
+			fmt.Println("Emulating a single sample requires the seed of its batch to be specified with -seed.")
+			return exitUsage
+		}
+		cfg.seed = time.Now().UnixNano()
+	}
+
+	if cfg.asmFile == "" {
+		fmt.Println("An assembly file must be specified with -asm.")
+		return exitUsage
+	}
+	if cfg.eTol <= 0 {
+		fmt.Println("Errors to tolerate must be greater than 0.")
+		return exitUsage
+	}
+	isa, e := emu.ParseISA(opts.isa)
+	if e != nil {
+		fmt.Println("The instruction set must be mips1 or mips32r2.")
+		return exitUsage
+	}
+	cfg.isa = isa
+	if opts.inputFile != "" {
+		if cfg.input != "" {
+			fmt.Println("The input can be given by -input or -input-text, not both.")
+			return exitUsage
+		}
+		b, e := ioutil.ReadFile(opts.inputFile)
+		if e != nil {
+			fmt.Println("ERROR: Failed to open input file: " + e.Error())
+			return exitFileAccess
+		}
+		cfg.input = string(b)
+	} else {
+		cfg.input = strings.ReplaceAll(cfg.input, "\\n", "\n")
+	}
+	if opts.keysFile != "" {
+		if cfg.keys != "" {
+			fmt.Println("The keys can be given by -keys or -keys-text, not both.")
+			return exitUsage
+		}
+		b, e := ioutil.ReadFile(opts.keysFile)
+		if e != nil {
+			fmt.Println("ERROR: Failed to open keys file: " + e.Error())
+			return exitFileAccess
+		}
+		cfg.keys = string(b)
+	} else {
+		cfg.keys = strings.ReplaceAll(cfg.keys, "\\n", "\n")
+	}
+	if opts.bitmap != "" {
+		settings, e := parseBitmapSettings(opts)
+		if e != nil {
+			fmt.Println("Invalid bitmap display: " + e.Error())
+			return exitUsage
+		}
+		cfg.bitmap = settings
+	} else if cfg.bitmapPNG != "" || cfg.bitmapGIF != "" {
+		fmt.Println("Saving the bitmap display requires its size to be specified with -bitmap.")
+		return exitUsage
+	}
+	if cfg.profileOut != "" {
+		cfg.profile = true
+	}
+	if cfg.callsOut != "" {
+		cfg.calls = true
+	}
+	if cfg.coverageOut != "" {
+		cfg.coverage = true
+	}
+	if cfg.trace < 0 {
+		fmt.Println("The size of the trace cannot be negative.")
+		return exitUsage
+	} else if cfg.traceOut != "" && cfg.trace == 0 {
+		cfg.trace = defaultTraceSize
+	}
+	if cfg.limit <= 0 {
+		fmt.Println("The runtime limit must be greater than 0.")
+		return exitUsage
+	}
+	if cfg.workers < 0 {
+		fmt.Println("The number of workers cannot be negative.")
+		return exitUsage
+	}
+	if cfg.numSamples < 0 {
+		fmt.Println("The number of samples cannot be negative.")
+		return exitUsage
+	}
+
+	if cfg.assignment != "" && vet.Find(cfg.assignment) == nil {
+		fmt.Println("Unknown assignment to vet: " + cfg.assignment)
+		return exitUsage
+	}
+
+	if cfg.numSamples == 0 {
+		if cfg.assignment != "" {
+			cfg.numSamples = defaultVetCount
+		} else {
+			cfg.numSamples = 1
+		}
+	}
+
+	if !validateEulaNonInteractive(opts.agreeEula) {
+		return exitEula
+	}
+
+	return runSession(cfg)
+}
+
+//parses the bitmap display flags
+func parseBitmapSettings(opts *vetOptions) (*emu.FramebufferSettings, error) {
+	settings := new(emu.FramebufferSettings)
+	size := strings.Split(strings.ToLower(opts.bitmap), "x")
+	if len(size) != 2 {
+		return nil, fmt.Errorf("the size must be in the form [width]x[height], such as 64x64")
+	}
+	var e error
+	if settings.Width, e = strconv.Atoi(size[0]); e != nil {
+		return nil, fmt.Errorf("invalid width \"%s\"", size[0])
+	}
+	if settings.Height, e = strconv.Atoi(size[1]); e != nil {
+		return nil, fmt.Errorf("invalid height \"%s\"", size[1])
+	}
+
+	base, e := strconv.ParseUint(opts.bitmapBase, 0, 32)
+	if e != nil {
+		return nil, fmt.Errorf("invalid base address \"%s\"", opts.bitmapBase)
+	}
+	settings.Base = uint32(base)
+
+	if settings.Format, e = emu.ParsePixelFormat(opts.pixels); e != nil {
+		return nil, e
+	}
+	if opts.cfg.bitmapScale <= 0 || opts.cfg.frameInterval < 0 {
+		return nil, fmt.Errorf("the scale must be positive and the frame interval cannot be negative")
+	}
+	settings.Scale = opts.cfg.bitmapScale
+	settings.FrameInterval = uint32(opts.cfg.frameInterval)
+	if opts.cfg.bitmapGIF != "" && settings.FrameInterval == 0 {
+		settings.FrameInterval = defaultFrameInterval
+	}
+
+	//creating one to check the settings
+	if _, e = emu.NewFramebuffer(*settings); e != nil {
+		return nil, e
+	}
+	return settings, nil
+}
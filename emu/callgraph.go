@@ -0,0 +1,78 @@
+package emu
+
+/**
+ * Call profile
+ * An optional shadow call stack, enabled with EnableCallProfile. Calls (jal, jalr, and bltzal and bgezal when taken)
+ * enter the function at their target and jr $31 returns from it, and every instruction is counted towards the
+ * function on the top of the stack.
+ *
+ * The stack is kept as a calling context tree: a node for every distinct path of calls from the entry point, so
+ * the same function called from two places has two nodes. The flat profile, the call graph and the collapsed stacks
+ * of flame graphs can all be derived from it (see vet.CallProfile).
+ */
+
+//CallNode is a function called from the path of its ancestors, the root is the entry point of the program
+type CallNode struct {
+	Function uint32 //the address of the function, the target of the call
+	Calls    uint64 //the number of times the function was called from its parent
+	Self     uint64 //the number of instructions executed in the function, not counting its callees
+	Children map[uint32]*CallNode
+	Parent   *CallNode
+}
+
+//NewCallNode creates a node for the function, called from the parent (nil for the root)
+func NewCallNode(function uint32, parent *CallNode) *CallNode {
+	return &CallNode{
+		Function: function,
+		Children: make(map[uint32]*CallNode),
+		Parent:   parent,
+	}
+}
+
+//Child returns the node of the function called from this one, creating it if needed
+func (n *CallNode) Child(function uint32) *CallNode {
+	c, ok := n.Children[function]
+	if !ok {
+		c = NewCallNode(function, n)
+		n.Children[function] = c
+	}
+
+	return c
+}
+
+//Total returns the number of instructions executed in the function and its callees
+func (n *CallNode) Total() uint64 {
+	ret := n.Self
+	for _, c := range n.Children {
+		ret += c.Total()
+	}
+
+	return ret
+}
+
+//EnableCallProfile keeps a shadow call stack and counts the instructions executed in each function, see
+//EmulationResult.CallTree. Not enabled by default as it slows the emulation down
+func (inst *Machine) EnableCallProfile() {
+	inst.callTree = NewCallNode(inst.pc, nil)
+	inst.callNode = inst.callTree
+}
+
+//enters the function at the target, if the call profile is enabled
+func (inst *Machine) enterCall(target uint32) {
+	if inst.callNode == nil {
+		return
+	}
+
+	inst.callNode = inst.callNode.Child(target)
+	inst.callNode.Calls++
+}
+
+//returns from the current function, if the call profile is enabled. Returning from the entry point ends the program,
+//so the root is never left
+func (inst *Machine) returnCall() {
+	if inst.callNode == nil || inst.callNode.Parent == nil {
+		return
+	}
+
+	inst.callNode = inst.callNode.Parent
+}
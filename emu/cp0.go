@@ -0,0 +1,120 @@
+package emu
+
+/**
+ * Coprocessor 0
+ * An optional exception model, enabled with EnableExceptions. Without it, every fault is reported as a runtime error
+ * and the emulation continues with the next instruction. With it, the faults that real hardware traps on (overflow,
+ * address errors, reserved instructions, syscall and break) instead vector to the kernel's exception handler, which
+ * can inspect Status, Cause, EPC and BadVAddr with mfc0 and return with eret.
+ *
+ * A fault inside the handler (while Status.EXL is set) cannot be handled and is reported as a runtime error.
+ */
+
+//the coprocessor 0 registers, indexed by register number
+const (
+	CP0BadVAddr = 8
+	CP0Status   = 12
+	CP0Cause    = 13
+	CP0EPC      = 14
+)
+
+//exception codes, stored in the ExcCode field of Cause
+const (
+	ExcAdEL = 4  //address error on a load or instruction fetch
+	ExcAdES = 5  //address error on a store
+	ExcSys  = 8  //syscall
+	ExcBp   = 9  //break
+	ExcRI   = 10 //reserved instruction
+	ExcOv   = 12 //arithmetic overflow
+)
+
+const (
+	statusEXL      = 0x2
+	causeExcCode   = 0x7C
+	statusInitial  = 0x0000FF11 //as in MARS: interrupts enabled and unmasked, user mode
+	cp0Unavailable = "coprocessor 0 instructions require an exception handler (a .ktext segment)"
+)
+
+//EnableExceptions turns on the exception model, with faults vectoring to the handler address
+func (inst *Machine) EnableExceptions(handler uint32) {
+	inst.exceptions = true
+	inst.excHandler = handler
+	inst.cp0[CP0Status] = statusInitial
+}
+
+//reports the fault as a runtime error, or raises the exception when the exception model is enabled and the handler is
+//not already running. badVAddr is only recorded for address errors
+func (inst *Machine) fault(excCode int, badVAddr uint32, eType int, format string, fArgs ...interface{}) {
+	if !inst.exceptions || inst.cp0[CP0Status]&statusEXL != 0 {
+		inst.ReportError(eType, format, fArgs...)
+		return
+	}
+
+	if excCode == ExcAdEL || excCode == ExcAdES {
+		inst.writeCP0(CP0BadVAddr, badVAddr)
+	}
+	inst.writeCP0(CP0Cause, inst.cp0[CP0Cause]&^causeExcCode|uint32(excCode)<<2)
+	inst.writeCP0(CP0EPC, inst.pc) //the faulting instruction, the handler adds 4 to skip it
+	inst.writeCP0(CP0Status, inst.cp0[CP0Status]|statusEXL)
+	inst.pc = inst.excHandler - 4 //accounting for the increment
+}
+
+//writes the coprocessor 0 register, logging it for Undo
+func (inst *Machine) writeCP0(reg int, data uint32) {
+	if inst.undoing {
+		inst.logCP0Write(reg)
+	}
+	inst.cp0[reg] = data
+}
+
+//executes mfc0, mtc0 and eret. rd and fn are within the immediate
+func (inst *Machine) executeCOP0(rs, rt int, imm uint32) {
+	if !inst.exceptions {
+		inst.fault(ExcRI, 0, EInvalidInstruction, cp0Unavailable)
+		return
+	}
+
+	rd := int(imm>>11) & 0x1F
+	switch rs {
+	case RsMFC0:
+		inst.RegWrite(rt, inst.cp0[rd])
+		break
+	case RsMTC0:
+		if rd == CP0BadVAddr {
+			//read only
+			break
+		}
+		inst.writeCP0(rd, inst.RegAccess(rt))
+		break
+	case RsCO:
+		if int(imm&0x3F) != FnERET {
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid coprocessor 0 operation", imm&0x3F)
+			break
+		}
+		inst.writeCP0(CP0Status, inst.cp0[CP0Status]&^statusEXL)
+		inst.pc = inst.cp0[CP0EPC] - 4 //accounting for the increment
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid coprocessor 0 instruction", rs)
+	}
+}
+
+//DecodeExceptionCode returns the name of an exception code, as in the Cause register
+func DecodeExceptionCode(code int) string {
+	switch code {
+	case ExcAdEL:
+		return "AdEL"
+	case ExcAdES:
+		return "AdES"
+	case ExcSys:
+		return "Sys"
+	case ExcBp:
+		return "Bp"
+	case ExcRI:
+		return "RI"
+	case ExcOv:
+		return "Ov"
+	}
+
+	return "unknown"
+}
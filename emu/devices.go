@@ -0,0 +1,85 @@
+package emu
+
+import "fmt"
+
+/**
+ * Memory-mapped I/O
+ * A device claims a range of addresses, and every load and store within the range is handled by the device instead
+ * of the memory (before the caches and the page lookup). Devices are attached to a machine before it runs, and
+ * each emulation needs its own devices as they keep state.
+ *
+ * Devices are accessed a word at a time: the address is word aligned, and stores give the mask of the bytes written
+ * as with MemWrite. Device registers are never uninitialized.
+ *
+ * The state of a device lives outside of the machine, so Undo cannot undo an access to it unless the device implements
+ * UndoableDevice, as the built-in devices do.
+ */
+
+//Device is a memory-mapped I/O device
+type Device interface {
+	//Range returns the first and last addresses claimed by the device
+	Range() (first, last uint32)
+	//Read returns the word at the (word aligned) address
+	Read(inst *Machine, addr uint32) uint32
+	//Write writes the masked bits of data to the word at the (word aligned) address
+	Write(inst *Machine, addr, data, mask uint32)
+}
+
+//UndoableDevice is optionally implemented by devices whose accesses can be undone (see Machine.Undo)
+type UndoableDevice interface {
+	Device
+	//SaveState returns the part of the state that an access to the (word aligned) address can change
+	SaveState(addr uint32) interface{}
+	//RestoreState restores a state returned by SaveState
+	RestoreState(state interface{})
+}
+
+type mappedDevice struct {
+	first, last uint32
+	device      Device
+}
+
+//AttachDevice maps the device into the address space of the machine, its range cannot overlap another device
+func (inst *Machine) AttachDevice(d Device) error {
+	first, last := d.Range()
+	if last < first {
+		return fmt.Errorf("the device range 0x%X to 0x%X is empty", first, last)
+	}
+	for _, m := range inst.devices {
+		if first <= m.last && m.first <= last {
+			return fmt.Errorf("the device range 0x%X to 0x%X overlaps another device", first, last)
+		}
+	}
+
+	inst.devices = append(inst.devices, mappedDevice{first: first, last: last, device: d})
+	if len(inst.devices) == 1 || first < inst.devLow {
+		inst.devLow = first
+	}
+	return nil
+}
+
+//Devices returns the attached devices, in the order they were attached
+func (inst *Machine) Devices() []Device {
+	ret := make([]Device, len(inst.devices))
+	for i, m := range inst.devices {
+		ret[i] = m.device
+	}
+
+	return ret
+}
+
+//returns the device that claims the address, or nil if it is memory
+func (inst *Machine) deviceAt(addr uint32) Device {
+	if len(inst.devices) == 0 || addr < inst.devLow {
+		//the common case, kept cheap as it is checked on every access
+		return nil
+	}
+
+	for _, m := range inst.devices {
+		if addr >= m.first && addr <= m.last {
+			return m.device
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package emu
+
+import "fmt"
+
+/**
+ * Disassembler
+ * Turns a machine word back into assembly, for the instruction trace. Registers are given by their O32 names and
+ * branch and jump targets as absolute addresses, as the labels are not known here. Words that are not a valid
+ * instruction are shown as ".word".
+ */
+
+//the names of the R-type instructions, by function field
+var rTypeNames = map[int]string{
+	FnADD: "add", FnADDU: "addu", FnSUB: "sub", FnSUBU: "subu", FnAND: "and", FnOR: "or", FnXOR: "xor",
+	FnNOR: "nor", FnSLT: "slt", FnSLTU: "sltu", FnMOVZ: "movz", FnMOVN: "movn",
+}
+
+//the names of the I-type arithmetic instructions, by op code
+var iTypeNames = map[int]string{
+	OpADDI: "addi", OpADDIU: "addiu", OpSLTI: "slti", OpSLTIU: "sltiu", OpANDI: "andi", OpORI: "ori", OpXORI: "xori",
+}
+
+//the names of the loads and stores, by op code
+var memoryNames = map[int]string{
+	OpLB: "lb", OpLBU: "lbu", OpLH: "lh", OpLHU: "lhu", OpLW: "lw", OpLWL: "lwl", OpLWR: "lwr", OpSB: "sb",
+	OpSH: "sh", OpSW: "sw", OpSWL: "swl", OpSWR: "swr",
+}
+
+//the names of the floating-point arithmetic, by function field
+var fpNames = map[int]string{
+	FnFADD: "add", FnFSUB: "sub", FnFMUL: "mul", FnFDIV: "div", FnFSQRT: "sqrt", FnFABS: "abs", FnFMOV: "mov",
+	FnFNEG: "neg", FnROUNDW: "round.w", FnTRUNCW: "trunc.w", FnCEILW: "ceil.w", FnFLOORW: "floor.w",
+	FnCVTS: "cvt.s", FnCVTD: "cvt.d", FnCVTW: "cvt.w", FnCEQ: "c.eq", FnCLT: "c.lt", FnCLE: "c.le",
+}
+
+//Disassemble returns the assembly of the instruction at the address pc, such as "addi $t0, $t0, -1"
+func Disassemble(instr, pc uint32) string {
+	if instr == 0 {
+		return "nop"
+	}
+
+	op, x, y, z, imm, fn := DecodeInstruction(instr)
+	rs, rt, rd := RegisterName(x), RegisterName(y), RegisterName(z)
+	simm := int32(int16(imm))
+	branchTarget := pc + 4 + uint32(simm)<<2
+
+	switch op {
+	case 0x0:
+		if name, ok := rTypeNames[fn]; ok {
+			return fmt.Sprintf("%s %s, %s, %s", name, rd, rs, rt)
+		}
+		switch fn {
+		case FnSLL, FnSRA:
+			name := "sll"
+			if fn == FnSRA {
+				name = "sra"
+			}
+			return fmt.Sprintf("%s %s, %s, %d", name, rd, rt, imm)
+		case FnSRL:
+			if x == 1 {
+				return fmt.Sprintf("rotr %s, %s, %d", rd, rt, imm)
+			}
+			return fmt.Sprintf("srl %s, %s, %d", rd, rt, imm)
+		case FnSLLV, FnSRAV:
+			name := "sllv"
+			if fn == FnSRAV {
+				name = "srav"
+			}
+			return fmt.Sprintf("%s %s, %s, %s", name, rd, rt, rs)
+		case FnSRLV:
+			if imm == 1 {
+				return fmt.Sprintf("rotrv %s, %s, %s", rd, rt, rs)
+			}
+			return fmt.Sprintf("srlv %s, %s, %s", rd, rt, rs)
+		case FnMULT, FnMULTU, FnDIV, FnDIVU:
+			name := map[int]string{FnMULT: "mult", FnMULTU: "multu", FnDIV: "div", FnDIVU: "divu"}[fn]
+			return fmt.Sprintf("%s %s, %s", name, rs, rt)
+		case FnMFHI:
+			return "mfhi " + rd
+		case FnMFLO:
+			return "mflo " + rd
+		case FnMTHI:
+			return "mthi " + rs
+		case FnMTLO:
+			return "mtlo " + rs
+		case FnJR:
+			return "jr " + rs
+		case FnJALR:
+			return fmt.Sprintf("jalr %s, %s", rd, rs)
+		case FnSYSCALL:
+			return "syscall"
+		case FnBREAK:
+			return fmt.Sprintf("break %d", instr>>6&0xFFFFF)
+		}
+		break
+	case OpJ, OpJAL:
+		name := "j"
+		if op == OpJAL {
+			name = "jal"
+		}
+		return fmt.Sprintf("%s 0x%X", name, (pc+4)&0xF0000000|imm<<2)
+	case OpBEQ, OpBNE:
+		name := "beq"
+		if op == OpBNE {
+			name = "bne"
+		}
+		return fmt.Sprintf("%s %s, %s, 0x%X", name, rs, RegisterName(z), branchTarget)
+	case OpBLEZ, OpBGTZ:
+		name := "blez"
+		if op == OpBGTZ {
+			name = "bgtz"
+		}
+		return fmt.Sprintf("%s %s, 0x%X", name, rs, branchTarget)
+	case OpREGIMM:
+		name, ok := map[int]string{RtBLTZ: "bltz", RtBGEZ: "bgez", RtBLTZAL: "bltzal", RtBGEZAL: "bgezal"}[z]
+		if ok {
+			return fmt.Sprintf("%s %s, 0x%X", name, rs, branchTarget)
+		}
+		break
+	case OpLUI:
+		return fmt.Sprintf("lui %s, 0x%X", RegisterName(z), imm)
+	case OpSWI:
+		return fmt.Sprintf("swi %d", imm)
+	case OpLWC1, OpSWC1, OpLDC1, OpSDC1:
+		name := map[int]string{OpLWC1: "lwc1", OpSWC1: "swc1", OpLDC1: "ldc1", OpSDC1: "sdc1"}[op]
+		return fmt.Sprintf("%s $f%d, %d(%s)", name, z, simm, rs)
+	case OpCOP0:
+		//decoded as an I-type instruction, rt is z and rd is in the immediate
+		switch x {
+		case RsMFC0:
+			return fmt.Sprintf("mfc0 %s, $%d", RegisterName(z), imm>>11&0x1F)
+		case RsMTC0:
+			return fmt.Sprintf("mtc0 %s, $%d", RegisterName(z), imm>>11&0x1F)
+		case RsCO:
+			if int(imm&0x3F) == FnERET {
+				return "eret"
+			}
+		}
+		break
+	case OpCOP1:
+		return disassembleCOP1(x, y, z, imm, fn, branchTarget)
+	case OpSPECIAL2:
+		switch fn {
+		case FnMUL:
+			return fmt.Sprintf("mul %s, %s, %s", rd, rs, rt)
+		case FnMADD, FnMADDU, FnMSUB, FnMSUBU:
+			name := map[int]string{FnMADD: "madd", FnMADDU: "maddu", FnMSUB: "msub", FnMSUBU: "msubu"}[fn]
+			return fmt.Sprintf("%s %s, %s", name, rs, rt)
+		case FnCLZ, FnCLO:
+			name := "clz"
+			if fn == FnCLO {
+				name = "clo"
+			}
+			return fmt.Sprintf("%s %s, %s", name, rd, rs)
+		}
+		break
+	case OpSPECIAL3:
+		switch fn {
+		case FnEXT:
+			return fmt.Sprintf("ext %s, %s, %d, %d", rt, rs, imm, z+1)
+		case FnINS:
+			return fmt.Sprintf("ins %s, %s, %d, %d", rt, rs, imm, z-int(imm)+1)
+		case FnBSHFL:
+			name, ok := map[uint32]string{ShWSBH: "wsbh", ShSEB: "seb", ShSEH: "seh"}[imm]
+			if ok {
+				return fmt.Sprintf("%s %s, %s", name, rd, rt)
+			}
+		}
+		break
+	default:
+		if name, ok := iTypeNames[op]; ok {
+			if op == OpANDI || op == OpORI || op == OpXORI {
+				return fmt.Sprintf("%s %s, %s, 0x%X", name, RegisterName(z), rs, imm)
+			}
+			return fmt.Sprintf("%s %s, %s, %d", name, RegisterName(z), rs, simm)
+		}
+		if name, ok := memoryNames[op]; ok {
+			return fmt.Sprintf("%s %s, %d(%s)", name, RegisterName(z), simm, rs)
+		}
+	}
+
+	return fmt.Sprintf(".word 0x%08X", instr)
+}
+
+//disassembles a COP1 instruction, where x is the format or operation, y is ft, z is fs and imm is fd
+func disassembleCOP1(x, y, z int, imm uint32, fn int, branchTarget uint32) string {
+	switch x {
+	case RsMFC1:
+		return fmt.Sprintf("mfc1 %s, $f%d", RegisterName(y), z)
+	case RsMTC1:
+		return fmt.Sprintf("mtc1 %s, $f%d", RegisterName(y), z)
+	case RsBC1:
+		name := "bc1f"
+		if y&1 == 1 {
+			name = "bc1t"
+		}
+		return fmt.Sprintf("%s %d, 0x%X", name, y>>2, branchTarget)
+	case FmtS, FmtD, FmtW:
+		name, ok := fpNames[fn]
+		if !ok {
+			break
+		}
+		format := map[int]string{FmtS: "s", FmtD: "d", FmtW: "w"}[x]
+		switch fn {
+		case FnFADD, FnFSUB, FnFMUL, FnFDIV:
+			return fmt.Sprintf("%s.%s $f%d, $f%d, $f%d", name, format, imm, z, y)
+		case FnCEQ, FnCLT, FnCLE:
+			return fmt.Sprintf("%s.%s %d, $f%d, $f%d", name, format, imm>>2, z, y)
+		}
+		return fmt.Sprintf("%s.%s $f%d, $f%d", name, format, imm, z)
+	}
+
+	return fmt.Sprintf(".word 0x%08X", uint32(OpCOP1)<<26|uint32(x)<<21|uint32(y)<<16|uint32(z)<<11|imm<<6|uint32(fn))
+}
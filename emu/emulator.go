@@ -0,0 +1,1193 @@
+/**
+ * Package emu is the emulator.
+ * The emulator is performance-oriented and does not keep track of what it does (compared to MiSaSiM)
+ * The emulator reads machine code and is of the Von-Nuemann model (meaning that instructions and data share the same
+ *   memory space)
+ * Because it reads from the actual memory, programs can dynamically edit the program memory which is something
+ *   MiSaSiM does not support.
+ * To allow for massive lookup tables to have improved performance, the emulator uses caching
+ *
+ * Emulate runs a program to completion. For finer control, New creates a Machine that can be stepped one instruction
+ * at a time.
+ */
+package emu
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"strings"
+)
+
+//runtime error types, see RuntimeError.EType
+const (
+	EUninitializedMemoryAccess int = iota
+	EUninitializedRegisterAccess
+	ERuntimeLimitExceeded
+	EInvalidInstruction
+	EIllegalRegisterWrite
+	EErrorLimitReached
+	EShiftOverflow
+	EHiLoUninitializedAccess
+	ESoftwareInterruptParameter
+	EInvalidSoftwareInterrupt
+	ESoftwareInterruptParameterValue
+	ENoAnswerReported
+	EDivideByZero
+	EArithmeticOverflow
+	EAddressErrorLoad  //AdEL, a misaligned load or instruction fetch
+	EAddressErrorStore //AdES, a misaligned store
+	EUnhandledException
+	EInvalidSyscall //an unsupported service or invalid arguments, see syscalls.go
+	ESyscallInput   //the input ran out or is not what the service reads
+	EDeviceAccess   //an invalid access to a memory-mapped device, see devices.go
+)
+
+//MemoryPage is one 4KB page of memory
+type MemoryPage struct {
+	StartAddr   uint32
+	Memory      []uint32 //is static-sized to the length of a page (4KB)
+	Initialized []uint32 //bits are set depending on if a word in memory has been initialized before reading
+}
+
+//SystemMemory is the memory of the whole system, keyed by the upper 20 bits of the page addresses
+type SystemMemory map[uint32]MemoryPage
+
+//MemoryImage is a contiguous block of words, such as the output of the assembler, to be loaded into SystemMemory
+type MemoryImage struct {
+	StartingAddr uint32
+	Memory       []uint32
+}
+
+type RuntimeError struct {
+	EType   int
+	Message string
+}
+
+//BranchInfo counts how many times a conditional branch was executed and how many of those it was taken
+type BranchInfo struct {
+	TotalCount  uint32
+	BranchCount uint32
+}
+
+//Machine is the state of a single emulation. It is created with New
+type Machine struct {
+	memory       SystemMemory
+	branchInfo   map[uint32]BranchInfo
+	pc           uint32
+	regs         [32]uint32
+	regInit      uint32
+	hiLoFilled   bool
+	hi, lo       uint32
+	iCache       MemoryPage
+	dCache       MemoryPage
+	dMissed      bool
+	di           uint32
+	runtimeLimit uint32
+	errorLimit   int
+	halted       bool
+	swiContext   interface{}
+	seed         int64
+	rng          *rand.Rand //all randomness during an emulation must come from here so that it is reproducible
+	isa          ISA
+	exceptions   bool //whether faults vector to the exception handler, see cp0.go
+	excHandler   uint32
+	cp0          [32]uint32
+	fpr          [32]uint32 //the floating-point registers, see fpu.go
+	fprInit      uint32
+	fcc          uint8 //the floating-point condition flags
+	syscalls     bool  //whether syscall performs the services in syscalls.go
+	input        string
+	inputPos     int
+	output       strings.Builder
+	heapBreak    uint32
+	exitCode     int
+	devices      []mappedDevice    //the memory-mapped devices, see devices.go
+	devLow       uint32            //the lowest address claimed by a device
+	profile      map[uint32]uint32 //the number of times each instruction was executed, nil unless profiling
+	callTree     *CallNode         //the calling context tree, nil unless profiling calls, see callgraph.go
+	callNode     *CallNode         //the function being executed
+	trace        []TraceEntry      //the ring buffer of the last instructions, nil unless tracing, see trace.go
+	traceNext    int
+	traceLen     int
+	traced       *TraceEntry //the entry of the instruction being executed
+	undoing      bool        //whether the instructions are logged so they can be undone, see undo.go
+	history      []undoRecord
+	rngSource    *splitMixSource
+
+	errors []RuntimeError //keeping the errors to return from emulation
+}
+
+//EmulationResult is the final (or current, see Machine.Result) state of an emulation
+type EmulationResult struct {
+	Memory         SystemMemory
+	Registers      [32]uint32
+	RegInit        uint32
+	Hi, Lo         uint32
+	HiLoFilled     bool
+	CP0            [32]uint32 //only used by the exception model, see Machine.EnableExceptions
+	FPRegisters    [32]uint32 //the bits of the floating-point registers
+	FPRegInit      uint32
+	FCC            uint8             //the floating-point condition flags
+	Output         string            //the console output of the syscall services
+	ExitCode       int               //set by the exit2 syscall
+	Devices        []Device          //the memory-mapped devices, with their final state
+	Profile        map[uint32]uint32 //the number of times each instruction was executed, nil unless profiled
+	CallTree       *CallNode         //the calling context tree, nil unless the calls were profiled
+	Trace          []TraceEntry      //the last instructions executed from the oldest, nil unless traced
+	PC             uint32
+	DI             uint32
+	Seed           int64 //the seed the emulation's random generator was created with
+	Sample         int   //the index of the sample within a batch, set by vet.RunBatch
+	SWIContext     interface{}
+	BranchAnalysis map[uint32]BranchInfo //keyed by the address of the conditional branch
+	Errors         []RuntimeError
+}
+
+/**
+ * The system memory is expressed as a map to improve emulator performance of systems that have spread out memory locations
+ *
+ * The key is the upper 20 bits of the memory address
+ * To improve emulator performance, the two memory pages will be cached
+ * 		One memory page will be for data, the other for instructions
+ * 		The instruction cache will be replaced if the instruction decoder has a cache miss once.
+ * 		The data cache will be replaced after two consecutive cache misses.
+ */
+
+//AddImage copies the image into the memory, creating pages as needed
+func (mem SystemMemory) AddImage(img *MemoryImage) {
+	currentPage := uint32(0xFFFFFFFF) //an invalid page to guarantee that the change of page code executes
+	for i := 0; len(img.Memory)*4 > i; i += 4 {
+		if ((img.StartingAddr+uint32(i))&0xFFFFF000)>>12 != currentPage {
+			//change of pages
+			currentPage = ((img.StartingAddr + uint32(i)) & 0xFFFFF000) >> 12
+
+			//checking if the map currently contains this page
+			_, ok := mem[currentPage]
+			if !ok {
+				mem[currentPage] = MemoryPage{
+					StartAddr:   currentPage << 12,
+					Memory:      make([]uint32, 1024),
+					Initialized: make([]uint32, 32),
+				}
+			}
+		}
+		mem[currentPage].Memory[((img.StartingAddr+uint32(i))%4096)/4] = img.Memory[i/4]
+		mem[currentPage].Initialized[((img.StartingAddr+uint32(i))%4096)/128] =
+			mem[currentPage].Initialized[((img.StartingAddr+uint32(i))%4096)/128] |
+				0x1<<((img.StartingAddr+uint32(i))/4%32) //setting this word to "initialized"
+	}
+}
+
+//Clone returns a deep copy of the memory
+func (mem SystemMemory) Clone() SystemMemory {
+	ret := make(SystemMemory)
+	for k, v := range mem {
+		newPage := MemoryPage{
+			StartAddr:   v.StartAddr,
+			Memory:      make([]uint32, len(v.Memory)),
+			Initialized: make([]uint32, len(v.Initialized)),
+		}
+
+		copy(newPage.Memory, v.Memory)
+		copy(newPage.Initialized, v.Initialized)
+
+		ret[k] = newPage
+	}
+
+	return ret
+}
+
+//ReportError adds a runtime error of the given type, accepts formatting
+func (inst *Machine) ReportError(eType int, format string, fArgs ...interface{}) {
+	eStr := fmt.Sprintf("ERROR: pc=0x%X di=%d message=%s", inst.pc, inst.di+1, fmt.Sprintf(format, fArgs...))
+	inst.errors = append(inst.errors, RuntimeError{
+		EType:   eType,
+		Message: eStr,
+	})
+}
+
+//Read returns the word at the address and whether it has been initialized
+func (mem SystemMemory) Read(addr uint32) (uint32, bool) {
+	page, ok := mem[addr>>12]
+	if !ok {
+		return 0, false
+	}
+
+	if (page.Initialized[(addr%4096)/128]>>((addr%4096)/4%32))&0x1 != 0x1 {
+		//not initialized
+		return 0, false
+	}
+
+	return page.Memory[addr/4%1024], true
+}
+
+//RegRead returns the register's value and whether it has been initialized
+func (r *EmulationResult) RegRead(reg int) (uint32, bool) {
+	if (r.RegInit>>reg)&0x1 != 0x1 {
+		return 0, false
+	}
+
+	return r.Registers[reg], true
+}
+
+//access functions
+
+func (inst *Machine) memAccess(addr uint32, isInstr bool) (uint32, bool) {
+	if inst.traced != nil && !isInstr {
+		v, ok := inst.readMemory(addr, isInstr)
+		inst.traceMemory(TraceLoad, addr, v)
+		return v, ok
+	}
+
+	return inst.readMemory(addr, isInstr)
+}
+
+func (inst *Machine) readMemory(addr uint32, isInstr bool) (uint32, bool) {
+	if d := inst.deviceAt(addr); d != nil {
+		if inst.undoing {
+			inst.logDevice(d, addr&0xFFFFFFFC)
+		}
+		return d.Read(inst, addr&0xFFFFFFFC), true
+	}
+
+	//checking cache first
+	if addr>>12 == inst.iCache.StartAddr>>12 {
+		//from instruction cache, checking if the value has been initialized
+		if (inst.iCache.Initialized[(addr%4096)/128]>>((addr%4096)/4%32))&0x1 != 0x1 {
+			//not initialized
+			inst.ReportError(EUninitializedMemoryAccess, "0x%X (%d) was accessed before it was initialized", addr, addr)
+			return 0, false
+		}
+
+		return inst.iCache.Memory[addr/4%1024], true
+	} else if addr>>12 == inst.dCache.StartAddr>>12 {
+		//from data cache, checking if the value has been initialized
+		if (inst.dCache.Initialized[(addr%4096)/128]>>((addr%4096)/4%32))&0x1 != 0x1 {
+			//not initialized
+			inst.ReportError(EUninitializedMemoryAccess, "0x%X (%d) was accessed before it was initialized", addr, addr)
+			return 0, false
+		}
+
+		inst.dMissed = false
+		return inst.dCache.Memory[addr/4%1024], true
+	}
+
+	page, ok := inst.memory[addr>>12]
+	if !ok {
+		inst.ReportError(EUninitializedMemoryAccess, "0x%X (%d) was accessed before it was initialized", addr, addr)
+		return 0, false
+	}
+
+	if (page.Initialized[(addr%4096)/128]>>((addr%4096)/4%32))&0x1 != 0x1 {
+		//not initialized
+		inst.ReportError(EUninitializedMemoryAccess, "0x%X (%d) was accessed before it was initialized", addr, addr)
+		return 0, false
+	}
+
+	if inst.undoing && (isInstr || inst.dMissed) {
+		inst.logCacheMiss()
+	}
+	if isInstr {
+		//cannot tolerate cache misses
+		inst.iCache = page
+
+		//checking if dCache is invalid
+		if inst.dCache.StartAddr == 1 {
+			//it is invalid
+			inst.dCache = page // just something to make it valid until it is assigned something
+		}
+	} else if inst.dMissed == true {
+		//already missed data cache once, needs to flush
+		inst.dCache = page
+	} else {
+		inst.dMissed = true
+	}
+
+	return page.Memory[addr/4%1024], true
+}
+
+//MemWrite writes the masked bits of data to the word at the address
+//mask and data should be shifted as per the address requirements before this function call
+func (inst *Machine) MemWrite(addr, data, mask uint32) {
+	inst.traceMemory(TraceStore, addr, data&mask)
+	if d := inst.deviceAt(addr); d != nil {
+		if inst.undoing {
+			inst.logDevice(d, addr&0xFFFFFFFC)
+		}
+		d.Write(inst, addr&0xFFFFFFFC, data, mask)
+		return
+	}
+	if inst.undoing {
+		inst.logMemWrite(addr, data, mask)
+	}
+
+	if addr>>12 == inst.iCache.StartAddr>>12 {
+		//to instruction cache
+		inst.iCache.Memory[addr/4%1024] = (data & mask) |
+			(inst.iCache.Memory[addr/4%1024] & (mask ^ 0xFFFFFFFF))
+
+		inst.iCache.Initialized[(addr%4096)/128] |= 0x1 << ((addr % 4096) / 4 % 32)
+
+		//instruction cache is not flushed from a write operation
+		return
+	} else if addr>>12 == inst.dCache.StartAddr>>12 {
+		//to data cache
+		inst.dCache.Memory[addr/4%1024] = (data & mask) |
+			(inst.dCache.Memory[addr/4%1024] & (mask ^ 0xFFFFFFFF))
+
+		inst.dCache.Initialized[(addr%4096)/128] |= 0x1 << ((addr % 4096) / 4 % 32)
+		inst.dMissed = false
+		return
+	}
+
+	//testing if the page exists yet
+	page, ok := inst.memory[addr>>12]
+	if !ok {
+		//need to create the page
+		page = MemoryPage{
+			StartAddr:   addr & 0xFFFFF000,
+			Memory:      make([]uint32, 1024),
+			Initialized: make([]uint32, 32),
+		}
+		inst.memory[addr>>12] = page
+	}
+
+	page.Memory[addr/4%1024] = (data & mask) | (page.Memory[addr/4%1024] & (mask ^ 0xFFFFFFFF))
+
+	page.Initialized[(addr%4096)/128] |= 0x1 << ((addr % 4096) / 4 % 32)
+}
+
+//RegInitialized returns true if the register has been written to
+func (inst *Machine) RegInitialized(reg int) bool {
+	return (inst.regInit>>reg)&0x1 == 0x1
+}
+
+//RegAccess returns the register's value, reporting an error if it has not been initialized
+func (inst *Machine) RegAccess(reg int) uint32 {
+	if (inst.regInit>>reg)&0x1 != 0x1 {
+		inst.ReportError(EUninitializedRegisterAccess, "%s was accessed before it was initialized", FormatRegister(reg))
+		return 0
+	}
+
+	return inst.regs[reg]
+}
+
+//RegWrite writes to the register, reporting an error if it is $0
+func (inst *Machine) RegWrite(reg int, data uint32) {
+	//setting initialized bit
+	if reg == 0 {
+		inst.ReportError(EIllegalRegisterWrite, "$0 ($zero) is immutable and cannot be written to")
+		return
+	}
+
+	if inst.undoing {
+		inst.logRegWrite(reg, false, data)
+	}
+	inst.regInit = inst.regInit | (0x1 << reg)
+	inst.regs[reg] = data
+	inst.traceReg(reg, false, data)
+}
+
+/**
+ * Emulation entry function
+ * 	Is multithreading friendly
+ * 	The seed is used for all randomness of the emulation (such as test case generation), so the same memory and
+ * 	seed will always produce the same result.
+ */
+func Emulate(startAddr uint32, mem SystemMemory, limit uint32, eTol int, seed int64) EmulationResult {
+	return New(startAddr, mem, limit, eTol, seed).Run()
+}
+
+//New creates a machine that starts executing at startAddr. The machine modifies mem as it runs.
+//The emulation ends after limit instructions or eTol errors, and all randomness comes from the seed.
+func New(startAddr uint32, mem SystemMemory, limit uint32, eTol int, seed int64) *Machine {
+	inst := new(Machine)
+	inst.memory = mem
+	inst.seed = seed
+	inst.rng, inst.rngSource = newEmulationRand(seed)
+	inst.regs[0] = 0           //reg 0 is an immutable zero.
+	inst.regs[31] = 0xFFFFFFFF //the program exit pc value
+	inst.regs[29] = 0x00100000 //the stack pointer register
+	inst.regInit = 0x1 | 0x1<<29 | 0x1<<31
+	inst.pc = startAddr & 0xFFFFFFFC //protection so that it always has the correct byte alignment
+	inst.hi = 0
+	inst.lo = 0
+	inst.hiLoFilled = false
+	inst.runtimeLimit = limit
+	inst.branchInfo = make(map[uint32]BranchInfo)
+	inst.errorLimit = eTol
+	inst.di = 0
+	inst.dCache = MemoryPage{
+		StartAddr:   1, //some arbitrary value to let the system know that the cache is invalid
+		Memory:      nil,
+		Initialized: nil,
+	}
+
+	//initializing instruction cache
+	if startAddr == 0 {
+		inst.iCache = inst.memory[0]
+	}
+
+	return inst
+}
+
+//SetISA selects the instructions the machine executes, MIPS1 by default. Instructions of a higher level are reported
+//as invalid instructions
+func (inst *Machine) SetISA(isa ISA) {
+	inst.isa = isa
+}
+
+//EnableProfile counts the number of times each instruction is executed, see EmulationResult.Profile. It is not
+//enabled by default as it slows the emulation down
+func (inst *Machine) EnableProfile() {
+	inst.profile = make(map[uint32]uint32)
+}
+
+//Run executes instructions until the emulation ends and returns the result
+func (inst *Machine) Run() EmulationResult {
+	for !inst.Halted() {
+		inst.Step()
+	}
+
+	return inst.Result()
+}
+
+//Halted returns true if the emulation has ended, reporting why the first time it is detected
+func (inst *Machine) Halted() bool {
+	if inst.halted {
+		return true
+	}
+
+	if inst.pc == 0xFFFFFFFF || len(inst.errors) >= inst.errorLimit || inst.di > inst.runtimeLimit {
+		if len(inst.errors) >= inst.errorLimit {
+			inst.ReportError(EErrorLimitReached, "maximum of %d errors has been exceeded, stopping emulation", inst.errorLimit)
+		} else if inst.di > inst.runtimeLimit {
+			inst.ReportError(ERuntimeLimitExceeded, "maximum runtime instruction count of %d exceeded", inst.runtimeLimit)
+		}
+		inst.halted = true
+	}
+
+	return inst.halted
+}
+
+//Step executes the instruction at the pc, the caller is responsible for checking that the emulation has not ended
+func (inst *Machine) Step() {
+	if inst.undoing {
+		inst.beginUndo()
+	}
+	if inst.pc%4 != 0 {
+		//such as after a jr to a misaligned address
+		if inst.exceptions && inst.cp0[CP0Status]&statusEXL == 0 {
+			inst.fault(ExcAdEL, inst.pc, EAddressErrorLoad, "instruction fetch from 0x%X, which is not word aligned", inst.pc)
+			inst.pc += 4 //to the handler
+			inst.di++
+			return
+		}
+
+		//there is no instruction to continue from
+		inst.ReportError(EAddressErrorLoad, "instruction fetch from 0x%X, which is not word aligned", inst.pc)
+		inst.halted = true
+		return
+	}
+
+	if inst.profile != nil {
+		inst.profile[inst.pc]++
+	}
+	if inst.callNode != nil {
+		inst.callNode.Self++
+	}
+	if inst.trace != nil {
+		inst.beginTrace()
+	}
+
+	//decode instruction
+	instr, ok := inst.memAccess(inst.pc, true)
+	if !ok {
+		//error already reported
+		inst.pc += 4
+		inst.di++
+		return
+	}
+	if inst.traced != nil {
+		inst.traced.Instruction = instr
+	}
+
+	op, x, y, z, imm, fn := DecodeInstruction(instr)
+
+	if instr == 0 {
+		//no-op, so do nothing
+	} else if op == 0x0 {
+		//R-type instruction where fn is the operation to perform
+		inst.executeRType(x, y, z, fn, imm)
+	} else if op == OpJ || op == OpJAL {
+		inst.executeJType(op, imm)
+	} else if op == OpSPECIAL2 || op == OpSPECIAL3 {
+		inst.executeR2(op, x, y, z, fn, imm)
+	} else if op == OpCOP0 {
+		inst.executeCOP0(x, z, imm)
+	} else if op == OpCOP1 {
+		inst.executeCOP1(x, y, z, fn, imm)
+	} else {
+		inst.executeIType(op, x, z, imm)
+	}
+
+	inst.di++
+	inst.pc += 4
+}
+
+//Result returns the current state of the emulation. The memory is shared with the machine, not copied
+func (inst *Machine) Result() EmulationResult {
+	return EmulationResult{
+		Memory:         inst.memory,
+		Registers:      inst.regs,
+		RegInit:        inst.regInit,
+		Hi:             inst.hi,
+		Lo:             inst.lo,
+		HiLoFilled:     inst.hiLoFilled,
+		CP0:            inst.cp0,
+		FPRegisters:    inst.fpr,
+		FPRegInit:      inst.fprInit,
+		FCC:            inst.fcc,
+		Output:         inst.output.String(),
+		ExitCode:       inst.exitCode,
+		Devices:        inst.Devices(),
+		Profile:        inst.profile,
+		CallTree:       inst.callTree,
+		Trace:          inst.traceEntries(),
+		PC:             inst.pc,
+		DI:             inst.di,
+		Seed:           inst.seed,
+		SWIContext:     inst.swiContext,
+		BranchAnalysis: inst.branchInfo,
+		Errors:         inst.errors,
+	}
+}
+
+//PC returns the address of the next instruction to execute
+func (inst *Machine) PC() uint32 {
+	return inst.pc
+}
+
+//DI returns the number of instructions executed so far
+func (inst *Machine) DI() uint32 {
+	return inst.di
+}
+
+//Seed returns the seed the emulation was created with
+func (inst *Machine) Seed() int64 {
+	return inst.seed
+}
+
+//MemPeek returns the word at the address and whether it is initialized, without reporting errors or reaching the
+//memory-mapped devices. Unlike Result, it is cheap enough to call after every instruction
+func (inst *Machine) MemPeek(addr uint32) (uint32, bool) {
+	return inst.memory.Read(addr)
+}
+
+//Registers returns the general purpose registers and the bits of the ones that are initialized
+func (inst *Machine) Registers() ([32]uint32, uint32) {
+	return inst.regs, inst.regInit
+}
+
+//HiLo returns the hi and lo registers and whether they have been written to
+func (inst *Machine) HiLo() (hi, lo uint32, filled bool) {
+	return inst.hi, inst.lo, inst.hiLoFilled
+}
+
+//FPRegisters returns the bits of the floating-point registers and the bits of the ones that are initialized
+func (inst *Machine) FPRegisters() ([32]uint32, uint32) {
+	return inst.fpr, inst.fprInit
+}
+
+//Errors returns the runtime errors reported so far
+func (inst *Machine) Errors() []RuntimeError {
+	return inst.errors
+}
+
+//Rand returns the emulation's random generator. All randomness during an emulation, such as the generation of
+//test cases by software interrupts, must come from it so that the emulation is reproducible
+func (inst *Machine) Rand() *rand.Rand {
+	return inst.rng
+}
+
+//SWIContext returns the value stored by the software interrupts, typically the test case of an assignment
+func (inst *Machine) SWIContext() interface{} {
+	return inst.swiContext
+}
+
+//SetSWIContext replaces the value stored by the software interrupts
+func (inst *Machine) SetSWIContext(ctx interface{}) {
+	if inst.undoing {
+		inst.logIrreversible()
+	}
+	inst.swiContext = ctx
+}
+
+func (inst *Machine) executeRType(x, y, z, fn int, shift uint32) {
+	switch fn {
+	case FnADD:
+		a, b := inst.RegAccess(x), inst.RegAccess(y)
+		res := a + b
+		if (a^res)&(b^res)&0x80000000 != 0 {
+			//the operands have the same sign and the result does not
+			inst.overflow("add", a, "+", b)
+			break
+		}
+		inst.RegWrite(z, res)
+		break
+	case FnADDU:
+		inst.RegWrite(z, inst.RegAccess(x)+inst.RegAccess(y))
+		break
+	case FnAND:
+		inst.RegWrite(z, inst.RegAccess(x)&inst.RegAccess(y))
+		break
+	case FnDIV:
+		if inst.RegAccess(y) == 0 {
+			inst.ReportError(EDivideByZero, "Cannot divide by zero.")
+			break
+		}
+		inst.lo = uint32(int32(inst.RegAccess(x)) / int32(inst.RegAccess(y)))
+		inst.hi = uint32(int32(inst.RegAccess(x)) % int32(inst.RegAccess(y)))
+		inst.hiLoFilled = true
+		break
+	case FnDIVU:
+		if inst.RegAccess(y) == 0 {
+			inst.ReportError(EDivideByZero, "Cannot divide by zero.")
+			break
+		}
+		inst.lo = inst.RegAccess(x) / inst.RegAccess(y)
+		inst.hi = inst.RegAccess(x) % inst.RegAccess(y)
+		inst.hiLoFilled = true
+		break
+	case FnJR:
+		inst.pc = inst.RegAccess(x) - 4 // the minus four is to account for the pc increment
+		if x == 31 {
+			inst.returnCall()
+		}
+		break
+	case FnMFHI:
+		if !inst.hiLoFilled {
+			inst.ReportError(EHiLoUninitializedAccess, "mfhi used on uninitialized result")
+		}
+		inst.RegWrite(z, inst.hi)
+		break
+	case FnMFLO:
+		if !inst.hiLoFilled {
+			inst.ReportError(EHiLoUninitializedAccess, "mflo used on uninitialized result")
+		}
+		inst.RegWrite(z, inst.lo)
+		break
+	case FnMULT:
+		res := int64(int32(inst.RegAccess(x))) * int64(int32(inst.RegAccess(y)))
+		inst.hi = uint32(res >> 32)
+		inst.lo = uint32(res)
+		inst.hiLoFilled = true
+		break
+	case FnMULTU:
+		res := uint64(inst.RegAccess(x)) * uint64(inst.RegAccess(y))
+		inst.hi = uint32(res >> 32)
+		inst.lo = uint32(res)
+		inst.hiLoFilled = true
+		break
+	case FnXOR:
+		inst.RegWrite(z, inst.RegAccess(x)^inst.RegAccess(y))
+		break
+	case FnOR:
+		inst.RegWrite(z, inst.RegAccess(x)|inst.RegAccess(y))
+		break
+	case FnSLT:
+		if int32(inst.RegAccess(x)) < int32(inst.RegAccess(y)) {
+			inst.RegWrite(z, 1)
+		} else {
+			inst.RegWrite(z, 0)
+		}
+		break
+	case FnSLTU:
+		if inst.RegAccess(x) < inst.RegAccess(y) {
+			inst.RegWrite(z, 1)
+		} else {
+			inst.RegWrite(z, 0)
+		}
+		break
+	case FnSLL:
+		inst.RegWrite(z, inst.RegAccess(y)<<shift)
+		break
+	case FnSRL:
+		if x == 1 && inst.isa >= MIPS32R2 {
+			//rotr
+			inst.RegWrite(z, bits.RotateLeft32(inst.RegAccess(y), -int(shift)))
+			break
+		}
+		inst.RegWrite(z, inst.RegAccess(y)>>shift)
+		break
+	case FnSRA:
+		inst.RegWrite(z, uint32(int32(inst.RegAccess(y))>>shift))
+		break
+	case FnSLLV:
+		amt := inst.RegAccess(x)
+		if amt > 31 {
+			inst.ReportError(EShiftOverflow, "%d is larger than the maximum shift amount of 31", amt)
+		}
+		inst.RegWrite(z, inst.RegAccess(y)<<(amt&0x1F))
+		break
+	case FnSRLV:
+		amt := inst.RegAccess(x)
+		if amt > 31 {
+			inst.ReportError(EShiftOverflow, "%d is larger than the maximum shift amount of 31", amt)
+		}
+		if shift == 1 && inst.isa >= MIPS32R2 {
+			//rotrv
+			inst.RegWrite(z, bits.RotateLeft32(inst.RegAccess(y), -int(amt&0x1F)))
+			break
+		}
+		inst.RegWrite(z, inst.RegAccess(y)>>(amt&0x1F))
+		break
+	case FnSRAV:
+		amt := inst.RegAccess(x)
+		if amt > 31 {
+			inst.ReportError(EShiftOverflow, "%d is larger than the maximum shift amount of 31", amt)
+		}
+		inst.RegWrite(z, uint32(int32(inst.RegAccess(y))>>(amt&0x1F)))
+		break
+	case FnSUB:
+		a, b := inst.RegAccess(x), inst.RegAccess(y)
+		res := a - b
+		if (a^b)&(a^res)&0x80000000 != 0 {
+			//the operands have different signs and the result does not have the sign of the first
+			inst.overflow("sub", a, "-", b)
+			break
+		}
+		inst.RegWrite(z, res)
+		break
+	case FnSUBU:
+		inst.RegWrite(z, inst.RegAccess(x)-inst.RegAccess(y))
+		break
+	case FnNOR:
+		inst.RegWrite(z, (inst.RegAccess(x)|inst.RegAccess(y))^0xFFFFFFFF)
+		break
+	case FnJALR:
+		target := inst.RegAccess(x) //read before linking in case rd is rs
+		inst.RegWrite(z, inst.pc+8) //there should be a nop instruction following the jalr
+		inst.pc = target - 4        //accounting for the increment
+		inst.enterCall(target)
+		break
+	case FnMTHI:
+		inst.hi = inst.RegAccess(x)
+		inst.hiLoFilled = true
+		break
+	case FnMTLO:
+		inst.lo = inst.RegAccess(x)
+		inst.hiLoFilled = true
+		break
+	case FnSYSCALL:
+		if inst.syscalls {
+			inst.executeSyscall()
+			break
+		}
+		inst.fault(ExcSys, 0, EUnhandledException, "syscall without an exception handler (a .ktext segment)")
+		break
+	case FnBREAK:
+		inst.fault(ExcBp, 0, EUnhandledException, "break %d without an exception handler (a .ktext segment)",
+			shift|uint32(z)<<5|uint32(y)<<10|uint32(x)<<15)
+		break
+	case FnMOVN, FnMOVZ:
+		if inst.isa < MIPS32R2 {
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%X is a MIPS32r2 function, which is not enabled", fn)
+			break
+		}
+		if (inst.RegAccess(y) != 0) == (fn == FnMOVN) {
+			inst.RegWrite(z, inst.RegAccess(x))
+		}
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid function for an R-type instruction", fn)
+	}
+}
+
+//executes the SPECIAL2 and SPECIAL3 instructions of MIPS32r2, which have the R-type format
+func (inst *Machine) executeR2(op, x, y, z, fn int, shift uint32) {
+	if inst.isa < MIPS32R2 {
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is a MIPS32r2 op code, which is not enabled", op)
+		return
+	}
+
+	if op == OpSPECIAL3 {
+		//the destination is rt (y)
+		switch fn {
+		case FnEXT:
+			//z is the size minus one and shift is the position
+			mask := uint32(0xFFFFFFFF) >> (31 - uint32(z))
+			inst.RegWrite(y, (inst.RegAccess(x)>>shift)&mask)
+			break
+		case FnINS:
+			//z is the last bit of the field and shift is the position
+			if uint32(z) < shift {
+				inst.fault(ExcRI, 0, EInvalidInstruction, "ins field ends at bit %d, before it starts at bit %d", z, shift)
+				break
+			}
+			mask := (uint32(0xFFFFFFFF) >> (31 - uint32(z) + shift)) << shift
+			inst.RegWrite(y, inst.RegAccess(y)&^mask|(inst.RegAccess(x)<<shift)&mask)
+			break
+		case FnBSHFL:
+			//the destination of the byte shuffles is rd (z) and the source is rt (y)
+			switch shift {
+			case ShWSBH:
+				v := inst.RegAccess(y)
+				inst.RegWrite(z, (v&0x00FF00FF)<<8|(v&0xFF00FF00)>>8)
+				break
+			case ShSEB:
+				inst.RegWrite(z, uint32(int32(int8(inst.RegAccess(y)))))
+				break
+			case ShSEH:
+				inst.RegWrite(z, uint32(int32(int16(inst.RegAccess(y)))))
+				break
+			default:
+				inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid byte shuffle", shift)
+			}
+			break
+		default:
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid function for a SPECIAL3 instruction", fn)
+		}
+		return
+	}
+
+	switch fn {
+	case FnMUL:
+		//hi and lo are unpredictable after mul in the spec, so they are left alone
+		inst.RegWrite(z, uint32(int32(inst.RegAccess(x))*int32(inst.RegAccess(y))))
+		break
+	case FnMADD, FnMADDU, FnMSUB, FnMSUBU:
+		if !inst.hiLoFilled {
+			inst.ReportError(EHiLoUninitializedAccess, "accumulating into uninitialized hi and lo")
+		}
+		acc := uint64(inst.hi)<<32 | uint64(inst.lo)
+		var res uint64
+		if fn == FnMADD || fn == FnMSUB {
+			res = uint64(int64(int32(inst.RegAccess(x))) * int64(int32(inst.RegAccess(y))))
+		} else {
+			res = uint64(inst.RegAccess(x)) * uint64(inst.RegAccess(y))
+		}
+		if fn == FnMADD || fn == FnMADDU {
+			acc += res
+		} else {
+			acc -= res
+		}
+		inst.hi = uint32(acc >> 32)
+		inst.lo = uint32(acc)
+		inst.hiLoFilled = true
+		break
+	case FnCLZ:
+		inst.RegWrite(z, uint32(bits.LeadingZeros32(inst.RegAccess(x))))
+		break
+	case FnCLO:
+		inst.RegWrite(z, uint32(bits.LeadingZeros32(^inst.RegAccess(x))))
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid function for a SPECIAL2 instruction", fn)
+	}
+}
+
+func (inst *Machine) executeIType(op, x, z int, imm uint32) {
+	switch op {
+	case OpADDI:
+		//sign extend the immediate
+		imm = uint32(int32(imm<<16) >> 16) //uses arithmetic shifting to copy the sign
+		a := inst.RegAccess(x)
+		res := a + imm
+		if (a^res)&(imm^res)&0x80000000 != 0 {
+			inst.overflow("addi", a, "+", imm)
+			break
+		}
+		inst.RegWrite(z, res)
+		break
+	case OpADDIU:
+		imm = uint32(int32(imm<<16) >> 16) //uses arithmetic shifting to copy the sign because it isn't actually unsigned (wtf mips..)
+		inst.RegWrite(z, inst.RegAccess(x)+imm)
+		break
+	case OpANDI:
+		inst.RegWrite(z, inst.RegAccess(x)&imm)
+		break
+	case OpBEQ:
+		inst.branchIf(inst.RegAccess(x) == inst.RegAccess(z), imm)
+		break
+	case OpBNE:
+		inst.branchIf(inst.RegAccess(x) != inst.RegAccess(z), imm)
+		break
+	case OpLB:
+		a := inst.effectiveAddr(x, imm)
+		v, _ := inst.memAccess(a, false)
+		v = v >> ((a % 4) * 8)
+		//sign extending the byte
+		v = uint32(int32((v&0xFF)<<24) >> 24)
+		inst.RegWrite(z, v)
+		break
+	case OpLBU:
+		a := inst.effectiveAddr(x, imm)
+		v, _ := inst.memAccess(a, false)
+		v = v >> ((a % 4) * 8)
+		inst.RegWrite(z, v&0xFF)
+		break
+	case OpLW:
+		a := inst.effectiveAddr(x, imm)
+		if !inst.aligned(a, 4, "lw", EAddressErrorLoad) {
+			break
+		}
+		v, _ := inst.memAccess(a, false)
+		inst.RegWrite(z, v)
+		break
+	case OpLUI:
+		inst.RegWrite(z, imm<<16)
+		break
+	case OpORI:
+		inst.RegWrite(z, inst.RegAccess(x)|imm)
+		break
+	case OpSB:
+		a := inst.effectiveAddr(x, imm)
+		b := inst.RegAccess(z) & 0xFF
+		b = b << ((a % 4) * 8)
+		inst.MemWrite(a, b, 0xFF<<((a%4)*8))
+		break
+	case OpSLTI:
+		imm = uint32(int32(imm<<16) >> 16) //uses arithmetic shifting to copy the sign
+		if int32(inst.RegAccess(x)) < int32(imm) {
+			inst.RegWrite(z, 1)
+		} else {
+			inst.RegWrite(z, 0)
+		}
+		break
+	case OpSLTIU:
+		imm = uint32(int32(imm<<16) >> 16) //sign extended, but then compared as unsigned
+		if inst.RegAccess(x) < imm {
+			inst.RegWrite(z, 1)
+		} else {
+			inst.RegWrite(z, 0)
+		}
+		break
+	case OpSW:
+		a := inst.effectiveAddr(x, imm)
+		if !inst.aligned(a, 4, "sw", EAddressErrorStore) {
+			break
+		}
+		inst.MemWrite(a, inst.RegAccess(z), 0xFFFFFFFF)
+		break
+	case OpSWI:
+		if inst.undoing {
+			inst.logIrreversible()
+		}
+		inst.dispatchSoftwareInterrupt(int(imm))
+		break
+	case OpXORI:
+		inst.RegWrite(z, inst.RegAccess(x)^imm)
+		break
+	case OpLH, OpLHU:
+		a := inst.effectiveAddr(x, imm)
+		if op == OpLH && !inst.aligned(a, 2, "lh", EAddressErrorLoad) ||
+			op == OpLHU && !inst.aligned(a, 2, "lhu", EAddressErrorLoad) {
+			break
+		}
+		v, _ := inst.memAccess(a, false)
+		v = (v >> ((a % 4) * 8)) & 0xFFFF
+		if op == OpLH {
+			//sign extending the half word
+			v = uint32(int32(v<<16) >> 16)
+		}
+		inst.RegWrite(z, v)
+		break
+	case OpSH:
+		a := inst.effectiveAddr(x, imm)
+		if !inst.aligned(a, 2, "sh", EAddressErrorStore) {
+			break
+		}
+		h := inst.RegAccess(z) & 0xFFFF
+		inst.MemWrite(a, h<<((a%4)*8), 0xFFFF<<((a%4)*8))
+		break
+	case OpLWL, OpLWR:
+		//merges the part of the unaligned word within the addressed word into the register (little endian)
+		a := inst.effectiveAddr(x, imm)
+		v, _ := inst.memAccess(a&0xFFFFFFFC, false)
+		b := a % 4
+		old := inst.regs[z] //an uninitialized register is not an error, it is about to be (partially) filled
+		if op == OpLWL {
+			inst.RegWrite(z, v<<(8*(3-b))|old&(0xFFFFFFFF>>(8*(b+1))))
+		} else {
+			inst.RegWrite(z, v>>(8*b)|old&(0xFFFFFFFF>>(8*b)^0xFFFFFFFF))
+		}
+		break
+	case OpSWL, OpSWR:
+		a := inst.effectiveAddr(x, imm)
+		v := inst.RegAccess(z)
+		b := a % 4
+		if op == OpSWL {
+			inst.MemWrite(a&0xFFFFFFFC, v>>(8*(3-b)), 0xFFFFFFFF>>(8*(3-b)))
+		} else {
+			inst.MemWrite(a&0xFFFFFFFC, v<<(8*b), 0xFFFFFFFF<<(8*b))
+		}
+		break
+	case OpLWC1, OpSWC1, OpLDC1, OpSDC1:
+		inst.executeFPMemory(op, x, z, imm)
+		break
+	case OpBLEZ:
+		inst.branchIf(int32(inst.RegAccess(x)) <= 0, imm)
+		break
+	case OpBGTZ:
+		inst.branchIf(int32(inst.RegAccess(x)) > 0, imm)
+		break
+	case OpREGIMM:
+		inst.executeRegImm(x, z, imm)
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid opcode for an instruction", op)
+	}
+}
+
+func (inst *Machine) executeJType(op int, imm uint32) {
+	//the target is within the 256MB region of the instruction after the jump
+	target := (inst.pc+4)&0xF0000000 | imm<<2
+	if op == OpJ {
+		inst.pc = target - 4 //accounting for the increment
+	} else if op == OpJAL {
+		inst.RegWrite(31, inst.pc+8) //there should be a nop instruction following the jal
+		inst.pc = target - 4         //accounting for the increment
+		inst.enterCall(target)
+	}
+}
+
+//the REGIMM branches, where rt selects the comparison of rs with zero
+func (inst *Machine) executeRegImm(x, rt int, imm uint32) {
+	v := int32(inst.RegAccess(x))
+	switch rt {
+	case RtBLTZ, RtBLTZAL:
+		if rt == RtBLTZAL {
+			inst.RegWrite(31, inst.pc+8) //linked even if the branch is not taken, a nop should follow
+		}
+		inst.branchIf(v < 0, imm)
+		if v < 0 && rt == RtBLTZAL {
+			inst.enterCall(inst.pc + 4) //the target, as Step is yet to add 4
+		}
+		break
+	case RtBGEZ, RtBGEZAL:
+		if rt == RtBGEZAL {
+			inst.RegWrite(31, inst.pc+8) //linked even if the branch is not taken, a nop should follow
+		}
+		inst.branchIf(v >= 0, imm)
+		if v >= 0 && rt == RtBGEZAL {
+			inst.enterCall(inst.pc + 4) //the target, as Step is yet to add 4
+		}
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid rt field for a REGIMM instruction", rt)
+	}
+}
+
+//reports the signed overflow of a trapping instruction. As in the spec, the destination register is not written
+func (inst *Machine) overflow(name string, a uint32, operator string, b uint32) {
+	inst.fault(ExcOv, 0, EArithmeticOverflow, "%s overflowed: %d %s %d does not fit in 32 signed bits, use %su if wraparound"+
+		" is intended", name, int32(a), operator, int32(b), name)
+}
+
+//reports an address error if the address of the access is not a multiple of its size. As in the spec, a misaligned
+//access is not performed
+func (inst *Machine) aligned(addr, size uint32, name string, eType int) bool {
+	if addr%size == 0 {
+		return true
+	}
+
+	excCode := ExcAdEL
+	if eType == EAddressErrorStore {
+		excCode = ExcAdES
+	}
+	inst.fault(excCode, addr, eType, "%s of 0x%X, which is not aligned to %d bytes", name, addr, size)
+	return false
+}
+
+//the address of a load or store, base register plus the sign-extended offset
+func (inst *Machine) effectiveAddr(x int, imm uint32) uint32 {
+	return inst.RegAccess(x) + uint32(int32(imm<<16)>>16)
+}
+
+//takes a branch. The immediate is a signed word offset relative to the instruction after the branch
+func (inst *Machine) branch(imm uint32) {
+	offset := uint32(int32(imm<<16) >> 14) //sign extended and multiplied by 4
+	inst.pc += offset                      //pc + 4 + offset, less the 4 added by Step
+}
+
+//a conditional branch, which is recorded in the branch analysis whether or not it is taken
+func (inst *Machine) branchIf(taken bool, imm uint32) {
+	if inst.undoing {
+		inst.logBranch()
+	}
+	info := inst.branchInfo[inst.pc]
+	info.TotalCount++
+	if taken {
+		info.BranchCount++
+	}
+	inst.branchInfo[inst.pc] = info
+
+	if taken {
+		inst.branch(imm)
+	}
+}
+
+//DecodeErrorCode returns the name of a runtime error type
+func DecodeErrorCode(iCode int) string {
+	/**
+	EUninitializedMemoryAccess int = iota
+	EUninitializedRegisterAccess
+	ERuntimeLimitExceeded
+	EInvalidInstruction
+	EIllegalRegisterWrite
+	EErrorLimitReached
+	EShiftOverflow
+	EHiLoUninitializedAccess
+	ESoftwareInterruptParameter
+	EInvalidSoftwareInterrupt
+	ESoftwareInterruptParameterValue
+	ENoAnswerReported
+	*/
+
+	switch iCode {
+	case EUninitializedMemoryAccess:
+		return "eUninitializedMemoryAccess"
+	case EUninitializedRegisterAccess:
+		return "eUninitializedRegisterAccess"
+	case ERuntimeLimitExceeded:
+		return "eRuntimeLimitExceeded"
+	case EInvalidInstruction:
+		return "eInvalidInstruction"
+	case EIllegalRegisterWrite:
+		return "eIllegalRegisterWrite"
+	case EErrorLimitReached:
+		return "eErrorLimitReached"
+	case EShiftOverflow:
+		return "eShiftOverflow"
+	case EHiLoUninitializedAccess:
+		return "eHiLoUninitializedAccess"
+	case ESoftwareInterruptParameter:
+		return "eSoftwareInterruptParameter"
+	case EInvalidSoftwareInterrupt:
+		return "eInvalidSoftwareInterrupt"
+	case ESoftwareInterruptParameterValue:
+		return "eSoftwareInterruptParameterValue"
+	case ENoAnswerReported:
+		return "eNoAnswerReported"
+	case EDivideByZero:
+		return "eDivideByZero"
+	case EArithmeticOverflow:
+		return "eArithmeticOverflow"
+	case EAddressErrorLoad:
+		return "eAddressErrorLoad"
+	case EAddressErrorStore:
+		return "eAddressErrorStore"
+	case EUnhandledException:
+		return "eUnhandledException"
+	case EInvalidSyscall:
+		return "eInvalidSyscall"
+	case ESyscallInput:
+		return "eSyscallInput"
+	case EDeviceAccess:
+		return "eDeviceAccess"
+	}
+
+	return "genericError"
+}
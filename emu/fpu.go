@@ -0,0 +1,292 @@
+package emu
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+ * Floating-point coprocessor (CP1)
+ * 32 single-precision registers, with doubles held in even/odd pairs (the low word in the even register) as in
+ * MIPS I. Like the general purpose registers, reading a floating-point register before it was written is reported as
+ * an uninitialized register access.
+ *
+ * There are 8 condition flags, set by the c.cond.fmt comparisons and tested by bc1t and bc1f. Conversions to words
+ * round to nearest (the default rounding mode), and values that do not fit become 0x7FFFFFFF as on real hardware.
+ */
+
+//FormatFPRegister describes the floating-point register, for example "$f2"
+func FormatFPRegister(reg int) string {
+	return fmt.Sprintf("$f%d", reg)
+}
+
+//FPRegRead returns the bits of the floating-point register and whether it has been initialized
+func (r *EmulationResult) FPRegRead(reg int) (uint32, bool) {
+	if (r.FPRegInit>>reg)&0x1 != 0x1 {
+		return 0, false
+	}
+
+	return r.FPRegisters[reg], true
+}
+
+//FPRegInitialized returns true if the floating-point register has been written to
+func (inst *Machine) FPRegInitialized(reg int) bool {
+	return (inst.fprInit>>reg)&0x1 == 0x1
+}
+
+//FPRegAccess returns the bits of the floating-point register, reporting an error if it has not been initialized
+func (inst *Machine) FPRegAccess(reg int) uint32 {
+	if (inst.fprInit>>reg)&0x1 != 0x1 {
+		inst.ReportError(EUninitializedRegisterAccess, "%s was accessed before it was initialized", FormatFPRegister(reg))
+		return 0
+	}
+
+	return inst.fpr[reg]
+}
+
+//FPRegWrite writes the bits to the floating-point register
+func (inst *Machine) FPRegWrite(reg int, data uint32) {
+	if inst.undoing {
+		inst.logRegWrite(reg, true, data)
+	}
+	inst.fprInit = inst.fprInit | (0x1 << reg)
+	inst.fpr[reg] = data
+	inst.traceReg(reg, true, data)
+}
+
+//reads the register as a single, or the register pair as a double
+func (inst *Machine) readFloat(reg int, double bool) float64 {
+	if double {
+		lo := inst.FPRegAccess(reg)
+		return math.Float64frombits(uint64(inst.FPRegAccess(reg+1))<<32 | uint64(lo))
+	}
+
+	return float64(math.Float32frombits(inst.FPRegAccess(reg)))
+}
+
+//writes the value to the register as a single (rounding it), or to the register pair as a double
+func (inst *Machine) writeFloat(reg int, double bool, v float64) {
+	if double {
+		bits := math.Float64bits(v)
+		inst.FPRegWrite(reg, uint32(bits))
+		inst.FPRegWrite(reg+1, uint32(bits>>32))
+		return
+	}
+
+	inst.FPRegWrite(reg, math.Float32bits(float32(v)))
+}
+
+//reports a reserved instruction if a double operand is not an even register, returns false if so
+func (inst *Machine) evenPair(double bool, regs ...int) bool {
+	if !double {
+		return true
+	}
+
+	for _, r := range regs {
+		if r%2 != 0 {
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%s is odd, double operands must be even-numbered registers",
+				FormatFPRegister(r))
+			return false
+		}
+	}
+
+	return true
+}
+
+//converts to a word, out of range values and NaN become 0x7FFFFFFF
+func floatToWord(v float64) uint32 {
+	if math.IsNaN(v) || v >= 2147483648 || v < -2147483648 {
+		return 0x7FFFFFFF
+	}
+
+	return uint32(int32(v))
+}
+
+//executes the COP1 instructions, which have the R-type format: fmt is the rs field, ft is rt, fs is rd and fd is the
+//shift amount
+func (inst *Machine) executeCOP1(fmtField, ft, fs, fn int, fd uint32) {
+	switch fmtField {
+	case RsMFC1:
+		inst.RegWrite(ft, inst.FPRegAccess(fs))
+		break
+	case RsMTC1:
+		inst.FPRegWrite(fs, inst.RegAccess(ft))
+		break
+	case RsBC1:
+		//the rt field holds the condition flag and whether to branch on true, the rest is the offset
+		cc := uint(ft >> 2)
+		onTrue := ft&0x1 == 0x1
+		inst.branchIf((inst.fcc>>cc&0x1 == 0x1) == onTrue, uint32(fs)<<11|fd<<6|uint32(fn))
+		break
+	case FmtS, FmtD:
+		inst.executeFPArithmetic(fmtField == FmtD, ft, fs, int(fd), fn)
+		break
+	case FmtW:
+		if !inst.evenPair(fn == FnCVTD, int(fd)) {
+			break
+		}
+		v := float64(int32(inst.FPRegAccess(fs)))
+		if fn == FnCVTS {
+			inst.writeFloat(int(fd), false, v)
+		} else if fn == FnCVTD {
+			inst.writeFloat(int(fd), true, v)
+		} else {
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid function for a word conversion", fn)
+		}
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid format for a floating-point instruction", fmtField)
+	}
+}
+
+func (inst *Machine) executeFPArithmetic(double bool, ft, fs, fd, fn int) {
+	if fn&0x30 == 0x30 {
+		//comparison, the condition flag is in the upper bits of fd
+		if !inst.evenPair(double, fs, ft) {
+			return
+		}
+		a, b := inst.readFloat(fs, double), inst.readFloat(ft, double)
+		var res bool
+		switch fn {
+		case FnCEQ:
+			res = a == b
+			break
+		case FnCLT:
+			res = a < b
+			break
+		case FnCLE:
+			res = a <= b
+			break
+		default:
+			inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a supported comparison", fn)
+			return
+		}
+
+		cc := uint(fd >> 2)
+		if res {
+			inst.fcc |= 0x1 << cc
+		} else {
+			inst.fcc &^= 0x1 << cc
+		}
+		return
+	}
+
+	switch fn {
+	case FnFADD, FnFSUB, FnFMUL, FnFDIV:
+		if !inst.evenPair(double, fd, fs, ft) {
+			break
+		}
+		a, b := inst.readFloat(fs, double), inst.readFloat(ft, double)
+		//computing a single in double precision and rounding it gives the correctly rounded single result
+		var res float64
+		switch fn {
+		case FnFADD:
+			res = a + b
+			break
+		case FnFSUB:
+			res = a - b
+			break
+		case FnFMUL:
+			res = a * b
+			break
+		case FnFDIV:
+			res = a / b
+			break
+		}
+		inst.writeFloat(fd, double, res)
+		break
+	case FnFSQRT, FnFABS, FnFMOV, FnFNEG:
+		if !inst.evenPair(double, fd, fs) {
+			break
+		}
+		if fn == FnFMOV {
+			//copied bit for bit
+			inst.FPRegWrite(fd, inst.FPRegAccess(fs))
+			if double {
+				inst.FPRegWrite(fd+1, inst.FPRegAccess(fs+1))
+			}
+			break
+		}
+		v := inst.readFloat(fs, double)
+		if fn == FnFSQRT {
+			v = math.Sqrt(v)
+		} else if fn == FnFABS {
+			v = math.Abs(v)
+		} else {
+			v = -v
+		}
+		inst.writeFloat(fd, double, v)
+		break
+	case FnROUNDW, FnTRUNCW, FnCEILW, FnFLOORW, FnCVTW:
+		if !inst.evenPair(double, fs) {
+			break
+		}
+		v := inst.readFloat(fs, double)
+		switch fn {
+		case FnROUNDW, FnCVTW:
+			v = math.RoundToEven(v)
+			break
+		case FnTRUNCW:
+			v = math.Trunc(v)
+			break
+		case FnCEILW:
+			v = math.Ceil(v)
+			break
+		case FnFLOORW:
+			v = math.Floor(v)
+			break
+		}
+		inst.FPRegWrite(fd, floatToWord(v))
+		break
+	case FnCVTS, FnCVTD:
+		//between singles and doubles, the source is in the format of the instruction
+		toDouble := fn == FnCVTD
+		if double == toDouble {
+			inst.fault(ExcRI, 0, EInvalidInstruction, "cannot convert a floating-point value to its own format")
+			break
+		}
+		if !inst.evenPair(double, fs) || !inst.evenPair(toDouble, fd) {
+			break
+		}
+		inst.writeFloat(fd, toDouble, inst.readFloat(fs, double))
+		break
+	default:
+		inst.fault(ExcRI, 0, EInvalidInstruction, "%X is not a valid function for a floating-point instruction", fn)
+	}
+}
+
+//executes lwc1, swc1, ldc1 and sdc1
+func (inst *Machine) executeFPMemory(op, x, ft int, imm uint32) {
+	a := inst.effectiveAddr(x, imm)
+	switch op {
+	case OpLWC1:
+		if !inst.aligned(a, 4, "lwc1", EAddressErrorLoad) {
+			break
+		}
+		v, _ := inst.memAccess(a, false)
+		inst.FPRegWrite(ft, v)
+		break
+	case OpSWC1:
+		if !inst.aligned(a, 4, "swc1", EAddressErrorStore) {
+			break
+		}
+		inst.MemWrite(a, inst.FPRegAccess(ft), 0xFFFFFFFF)
+		break
+	case OpLDC1:
+		if !inst.evenPair(true, ft) || !inst.aligned(a, 8, "ldc1", EAddressErrorLoad) {
+			break
+		}
+		lo, _ := inst.memAccess(a, false)
+		hi, _ := inst.memAccess(a+4, false)
+		inst.FPRegWrite(ft, lo)
+		inst.FPRegWrite(ft+1, hi)
+		break
+	case OpSDC1:
+		if !inst.evenPair(true, ft) || !inst.aligned(a, 8, "sdc1", EAddressErrorStore) {
+			break
+		}
+		inst.MemWrite(a, inst.FPRegAccess(ft), 0xFFFFFFFF)
+		inst.MemWrite(a+4, inst.FPRegAccess(ft+1), 0xFFFFFFFF)
+		break
+	}
+}
@@ -0,0 +1,259 @@
+package emu
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"strings"
+)
+
+/**
+ * Bitmap display
+ * A memory-mapped framebuffer like the MARS Bitmap Display: the pixels are stored row by row from the base address,
+ * and the image can be saved as a PNG, or as an animated GIF of the frames over time.
+ *
+ * Frames are recorded every FrameInterval instructions, but only when the framebuffer is written to, so a program
+ * that stops drawing does not grow the animation. The frame delay of the GIF is proportional to the number of
+ * instructions between frames.
+ */
+
+//PixelFormat is how a pixel is stored in the framebuffer
+type PixelFormat int
+
+const (
+	PixelRGB888 PixelFormat = iota //a word per pixel, 0x00RRGGBB as in MARS
+	PixelRGB565                    //a half word per pixel, 5 bits of red, 6 of green and 5 of blue
+	PixelGray8                     //a byte per pixel, 0 is black and 255 is white
+)
+
+const maxFrames = 1000 //protects against animations that would not fit in memory
+
+func (f PixelFormat) String() string {
+	switch f {
+	case PixelRGB888:
+		return "rgb888"
+	case PixelRGB565:
+		return "rgb565"
+	case PixelGray8:
+		return "gray8"
+	}
+
+	return "unknown"
+}
+
+//ParsePixelFormat returns the pixel format with the name, such as "rgb888"
+func ParsePixelFormat(name string) (PixelFormat, error) {
+	for _, f := range []PixelFormat{PixelRGB888, PixelRGB565, PixelGray8} {
+		if strings.ToLower(name) == f.String() {
+			return f, nil
+		}
+	}
+
+	return PixelRGB888, fmt.Errorf("unknown pixel format \"%s\", expected rgb888, rgb565 or gray8", name)
+}
+
+//the number of bytes per pixel
+func (f PixelFormat) size() int {
+	switch f {
+	case PixelRGB565:
+		return 2
+	case PixelGray8:
+		return 1
+	}
+
+	return 4
+}
+
+//FramebufferSettings describes a bitmap display
+type FramebufferSettings struct {
+	Base          uint32 //the address of the top left pixel, must be word aligned
+	Width, Height int    //in pixels
+	Format        PixelFormat
+	Scale         int    //each pixel is saved as a square of this size, as the unit width and height of MARS
+	FrameInterval uint32 //the number of instructions between frames of the animation, 0 to not record one
+}
+
+//Framebuffer is a memory-mapped bitmap display
+type Framebuffer struct {
+	settings  FramebufferSettings
+	words     []uint32
+	frames    [][]uint32 //copies of the words, the first after FrameInterval instructions
+	frameDI   []uint32   //the instruction count of each frame
+	nextFrame uint32
+}
+
+//NewFramebuffer creates a bitmap display
+func NewFramebuffer(settings FramebufferSettings) (*Framebuffer, error) {
+	if settings.Width <= 0 || settings.Height <= 0 {
+		return nil, fmt.Errorf("the bitmap display must be at least 1 by 1 pixels")
+	}
+	if settings.Base%4 != 0 {
+		return nil, fmt.Errorf("the base address of the bitmap display must be word aligned")
+	}
+	if settings.Scale <= 0 {
+		settings.Scale = 1
+	}
+
+	bytes := uint64(settings.Width) * uint64(settings.Height) * uint64(settings.Format.size())
+	if uint64(settings.Base)+bytes > 0x100000000 {
+		return nil, fmt.Errorf("the bitmap display does not fit in the address space")
+	}
+
+	return &Framebuffer{
+		settings:  settings,
+		words:     make([]uint32, (bytes+3)/4),
+		nextFrame: settings.FrameInterval,
+	}, nil
+}
+
+//Range returns the addresses of the pixels
+func (f *Framebuffer) Range() (uint32, uint32) {
+	return f.settings.Base, f.settings.Base + uint32(len(f.words))*4 - 1
+}
+
+//Read returns the word of pixels at the address
+func (f *Framebuffer) Read(inst *Machine, addr uint32) uint32 {
+	return f.words[(addr-f.settings.Base)/4]
+}
+
+//Write writes the masked bits of data to the word of pixels at the address
+func (f *Framebuffer) Write(inst *Machine, addr, data, mask uint32) {
+	if f.settings.FrameInterval != 0 && inst.DI() >= f.nextFrame && len(f.frames) < maxFrames {
+		//the frame is the framebuffer before this write
+		frame := make([]uint32, len(f.words))
+		copy(frame, f.words)
+		f.frames = append(f.frames, frame)
+		f.frameDI = append(f.frameDI, inst.DI())
+		f.nextFrame = (inst.DI()/f.settings.FrameInterval + 1) * f.settings.FrameInterval
+	}
+
+	i := (addr - f.settings.Base) / 4
+	f.words[i] = data&mask | f.words[i]&^mask
+}
+
+//the state of the framebuffer that an access can change: the word accessed and the recorded frames
+type framebufferState struct {
+	index     uint32
+	word      uint32
+	numFrames int
+	nextFrame uint32
+}
+
+//SaveState returns the word of pixels at the address and the number of frames recorded
+func (f *Framebuffer) SaveState(addr uint32) interface{} {
+	i := (addr - f.settings.Base) / 4
+	return framebufferState{index: i, word: f.words[i], numFrames: len(f.frames), nextFrame: f.nextFrame}
+}
+
+//RestoreState restores the state returned by SaveState
+func (f *Framebuffer) RestoreState(state interface{}) {
+	s := state.(framebufferState)
+	f.words[s.index] = s.word
+	f.frames = f.frames[:s.numFrames]
+	f.frameDI = f.frameDI[:s.numFrames]
+	f.nextFrame = s.nextFrame
+}
+
+//Settings returns the settings the display was created with
+func (f *Framebuffer) Settings() FramebufferSettings {
+	return f.settings
+}
+
+//returns the color of the pixel within the words
+func (f *Framebuffer) pixel(words []uint32, x, y int) color.RGBA {
+	size := f.settings.Format.size()
+	offset := (y*f.settings.Width + x) * size
+	v := words[offset/4] >> (uint(offset%4) * 8)
+
+	switch f.settings.Format {
+	case PixelRGB565:
+		r, g, b := v>>11&0x1F, v>>5&0x3F, v&0x1F
+		return color.RGBA{R: uint8(r<<3 | r>>2), G: uint8(g<<2 | g>>4), B: uint8(b<<3 | b>>2), A: 255}
+	case PixelGray8:
+		return color.RGBA{R: uint8(v), G: uint8(v), B: uint8(v), A: 255}
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+}
+
+func (f *Framebuffer) render(words []uint32) *image.RGBA {
+	scale := f.settings.Scale
+	img := image.NewRGBA(image.Rect(0, 0, f.settings.Width*scale, f.settings.Height*scale))
+	for y := 0; f.settings.Height > y; y++ {
+		for x := 0; f.settings.Width > x; x++ {
+			c := f.pixel(words, x, y)
+			for sy := y * scale; (y+1)*scale > sy; sy++ {
+				for sx := x * scale; (x+1)*scale > sx; sx++ {
+					img.SetRGBA(sx, sy, c)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+//Image returns the current contents of the display
+func (f *Framebuffer) Image() *image.RGBA {
+	return f.render(f.words)
+}
+
+//SavePNG saves the current contents of the display to the file
+func (f *Framebuffer) SavePNG(fileName string) error {
+	file, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the image: %s", e.Error())
+	}
+	defer file.Close()
+
+	if e = png.Encode(file, f.Image()); e != nil {
+		return fmt.Errorf("failed to encode the image: %s", e.Error())
+	}
+	return nil
+}
+
+//SaveGIF saves the recorded frames, followed by the current contents of the display, as an animation
+func (f *Framebuffer) SaveGIF(fileName string, endDI uint32) error {
+	if f.settings.FrameInterval == 0 {
+		return fmt.Errorf("frames were not recorded, a frame interval is required")
+	}
+
+	anim := &gif.GIF{}
+	frames := make([][]uint32, 0, len(f.frames)+1)
+	frames = append(append(frames, f.frames...), f.words)
+	times := make([]uint32, 0, len(f.frameDI)+1)
+	times = append(append(times, f.frameDI...), endDI)
+	for i, words := range frames {
+		img := f.render(words)
+		p := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(p, p.Rect, img, image.Point{}, draw.Src)
+
+		//each frame interval is shown for a tenth of a second, in hundredths of a second
+		delay := 200 //holding the final frame
+		if i+1 < len(times) {
+			delay = int(uint64(times[i+1]-times[i]) * 10 / uint64(f.settings.FrameInterval))
+			if delay < 2 {
+				delay = 2 //the shortest delay most viewers respect
+			}
+		}
+
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	file, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the animation: %s", e.Error())
+	}
+	defer file.Close()
+
+	if e = gif.EncodeAll(file, anim); e != nil {
+		return fmt.Errorf("failed to encode the animation: %s", e.Error())
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package emu
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ISA is the instruction set level that the assembler accepts and the emulator executes
+type ISA int
+
+const (
+	MIPS1    ISA = iota //the MIPS I integer instruction set of the course, the default
+	MIPS32R2            //MIPS I plus the MIPS32 Release 2 extensions (mul, madd, clz, movn, seb, ext, rotr...)
+)
+
+const (
+	OpADD   = 0x0  // R type
+	OpADDI  = 0x8  // I type
+	OpADDIU = 0x9  // I type
+	OpADDU  = 0x0  // R type
+	OpAND   = 0x0  // R type
+	OpANDI  = 0xC  // I type
+	OpBEQ   = 0x4  // I type
+	OpBNE   = 0x5  // I type
+	OpDIV   = 0x0  // R type
+	OpDIVU  = 0x0  // R type
+	OpJ     = 0x2  // J type
+	OpJAL   = 0x3  // J type
+	OpJR    = 0x0  // R type
+	OpLB    = 0x20 // I type
+	OpLBU   = 0x24 // I type
+	OpLUI   = 0xF  // I type
+	OpLW    = 0x23 // I type
+	OpMFHI  = 0x0  // R type
+	OpMFLO  = 0x0  // R type
+	OpMULT  = 0x0  // R type
+	OpMULTU = 0x0  // R type
+	OpXOR   = 0x0  // R type
+	OpOR    = 0x0  // R type
+	OpORI   = 0xD  // I type
+	OpSB    = 0x28 // I type
+	OpSLT   = 0x0  // R type
+	OpSLTI  = 0xA  // I type
+	OpSLTIU = 0xB  // I type
+	OpSLTU  = 0x0  // R type
+	OpSLL   = 0x0  // R type
+	OpSRL   = 0x0  // R type
+	OpSRA   = 0x0  // R type
+	OpSUB   = 0x0  // R type
+	OpSUBU  = 0x0  // R type
+	OpSW    = 0x2B // I type
+	OpSWI   = 0x2F // I type
+
+	OpNOR    = 0x0  // R type
+	OpJALR   = 0x0  // R type
+	OpMTHI   = 0x0  // R type
+	OpMTLO   = 0x0  // R type
+	OpLH     = 0x21 // I type
+	OpLHU    = 0x25 // I type
+	OpSH     = 0x29 // I type
+	OpLWL    = 0x22 // I type
+	OpLWR    = 0x26 // I type
+	OpSWL    = 0x2A // I type
+	OpSWR    = 0x2E // I type
+	OpXORI   = 0xE  // I type
+	OpBLEZ   = 0x6  // I type
+	OpBGTZ   = 0x7  // I type
+	OpREGIMM = 0x1  // I type, the rt field selects the branch (see RtBLTZ...)
+
+	OpSYSCALL = 0x0  // R type
+	OpBREAK   = 0x0  // R type, the code is in bits 6 to 25
+	OpCOP0    = 0x10 // the rs field selects the operation (see RsMFC0...)
+
+	OpCOP1 = 0x11 // R type, the rs field is the format (see FmtS...) or selects the operation (see RsMFC1...)
+	OpLWC1 = 0x31 // I type
+	OpSWC1 = 0x39 // I type
+	OpLDC1 = 0x35 // I type
+	OpSDC1 = 0x3D // I type
+
+	//MIPS32r2 only
+	OpSPECIAL2 = 0x1C // R type, mul, madd, maddu, msub, msubu, clz and clo
+	OpSPECIAL3 = 0x1F // R type, ext, ins and the byte shuffles (see FnBSHFL)
+	OpMOVN     = 0x0  // R type
+	OpMOVZ     = 0x0  // R type
+	OpROTR     = 0x0  // R type, srl with rs set to 1
+	OpROTRV    = 0x0  // R type, srlv with the shift amount set to 1
+)
+
+//the rs field of COP0 instructions
+const (
+	RsMFC0 = 0x00
+	RsMTC0 = 0x04
+	RsCO   = 0x10 //the function field selects the operation, such as FnERET
+)
+
+//the rs field of COP1 instructions
+const (
+	RsMFC1 = 0x00
+	RsMTC1 = 0x04
+	RsBC1  = 0x08 //bc1f and bc1t, the rt field is the condition flag shifted left by 2, plus 1 for bc1t
+	FmtS   = 0x10
+	FmtD   = 0x11
+	FmtW   = 0x14
+)
+
+//the function field of COP1 instructions with a format
+const (
+	FnFADD   = 0x00
+	FnFSUB   = 0x01
+	FnFMUL   = 0x02
+	FnFDIV   = 0x03
+	FnFSQRT  = 0x04
+	FnFABS   = 0x05
+	FnFMOV   = 0x06
+	FnFNEG   = 0x07
+	FnROUNDW = 0x0C
+	FnTRUNCW = 0x0D
+	FnCEILW  = 0x0E
+	FnFLOORW = 0x0F
+	FnCVTS   = 0x20
+	FnCVTD   = 0x21
+	FnCVTW   = 0x24
+	FnCEQ    = 0x32 //the condition flag is in the upper 3 bits of the fd field
+	FnCLT    = 0x3C
+	FnCLE    = 0x3E
+)
+
+//the rt field of REGIMM instructions
+const (
+	RtBLTZ   = 0x00
+	RtBGEZ   = 0x01
+	RtBLTZAL = 0x10
+	RtBGEZAL = 0x11
+)
+
+const (
+	FnADD   = 0x20
+	FnADDU  = 0x21
+	FnAND   = 0x24
+	FnDIV   = 0x1A
+	FnDIVU  = 0x1B
+	FnJR    = 0x08
+	FnMFHI  = 0x10
+	FnMFLO  = 0x12
+	FnMULT  = 0x18
+	FnMULTU = 0x19
+	FnXOR   = 0x26
+	FnOR    = 0x25
+	FnSLT   = 0x2A
+	FnSLTU  = 0x2B
+	FnSLL   = 0x00
+	FnSRL   = 0x02
+	FnSRA   = 0x03
+	FnSLLV  = 0x04
+	FnSRLV  = 0x05
+	FnSRAV  = 0x06
+	FnSUB   = 0x22
+	FnSUBU  = 0x23
+	FnNOR   = 0x27
+	FnJALR  = 0x09
+	FnMTHI  = 0x11
+	FnMTLO  = 0x13
+	FnMOVZ  = 0x0A
+	FnMOVN  = 0x0B
+
+	FnSYSCALL = 0x0C
+	FnBREAK   = 0x0D
+	FnERET    = 0x18 //COP0 with RsCO
+)
+
+//the function field of SPECIAL2 and SPECIAL3 instructions (MIPS32r2)
+const (
+	FnMADD  = 0x00
+	FnMADDU = 0x01
+	FnMUL   = 0x02
+	FnMSUB  = 0x04
+	FnMSUBU = 0x05
+	FnCLZ   = 0x20
+	FnCLO   = 0x21
+
+	FnEXT   = 0x00
+	FnINS   = 0x04
+	FnBSHFL = 0x20 //the shift amount field selects the shuffle, see ShWSBH...
+)
+
+//the shift amount field of BSHFL instructions
+const (
+	ShWSBH = 0x02
+	ShSEB  = 0x10
+	ShSEH  = 0x18
+)
+
+func (isa ISA) String() string {
+	if isa == MIPS32R2 {
+		return "mips32r2"
+	}
+
+	return "mips1"
+}
+
+//ParseISA returns the ISA level of its name, "mips1" or "mips32r2"
+func ParseISA(name string) (ISA, error) {
+	switch strings.ToLower(name) {
+	case "mips1", "mipsi":
+		return MIPS1, nil
+	case "mips32r2":
+		return MIPS32R2, nil
+	}
+
+	return MIPS1, fmt.Errorf("unknown ISA \"%s\", expected mips1 or mips32r2", name)
+}
+
+func FormRInstruction(opCode, rs, rt, rd, shift, funct int) uint32 {
+	return (uint32(opCode) << 26) | uint32(rs<<21) | uint32(rt<<16) | uint32(rd<<11) | uint32(shift<<6) | uint32(funct)
+}
+
+func FormIInstruction(opCode, rs, rt int, imm uint32) uint32 {
+	return (uint32(opCode) << 26) | uint32(rs<<21) | uint32(rt<<16) | (imm & 0xFFFF)
+}
+
+func FormJInstruction(opCode int, addr uint32) uint32 {
+	return (uint32(opCode) << 26) | addr
+}
+
+func DecodeInstruction(instr uint32) (op, x, y, z int, imm uint32, fn int) {
+	//last 6 bits are the op code and determine how to read the rest of the instruction
+	op = int(instr >> 26)
+	if op == 0x0 || op == OpSPECIAL2 || op == OpSPECIAL3 || op == OpCOP1 {
+		//R-type instruction where order is: op, rs, rt, rd, shift, fn
+		//rd is z, rs is x, rt is y
+		x = int((instr >> 21) & 0x1F)
+		y = int((instr >> 16) & 0x1F)
+		z = int((instr >> 11) & 0x1F)
+		imm = (instr >> 6) & 0x1F //doubles as shift amount
+		fn = int(instr & 0x3F)
+		return
+	} else if op == OpJ || op == OpJAL {
+		//J-type instruction where the order is op, addr
+		imm = instr & 0x03FFFFFF
+		return
+	} else {
+		//I-type instruction where order is: op, rs, rt, immediate
+		//rs is x, rt is z (the destination of loads and arithmetic)
+		x = int((instr >> 21) & 0x1F)
+		z = int((instr >> 16) & 0x1F)
+		imm = instr & 0xFFFF
+		return
+	}
+}
+
+//IsConditionalBranch returns true if the instruction is a conditional branch, which are recorded in the branch analysis
+func IsConditionalBranch(instr uint32) bool {
+	op, x, _, z, _, _ := DecodeInstruction(instr)
+	switch op {
+	case OpBEQ, OpBNE, OpBLEZ, OpBGTZ:
+		return true
+	case OpREGIMM:
+		return z == RtBLTZ || z == RtBGEZ || z == RtBLTZAL || z == RtBGEZAL
+	case OpCOP1:
+		return x == RsBC1
+	}
+
+	return false
+}
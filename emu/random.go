@@ -0,0 +1,40 @@
+package emu
+
+import "math/rand"
+
+func splitMix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+//DeriveSeed derives the seed of the nth of many emulations (such as the samples of a batch) from a single seed,
+//so that neighbouring emulations get unrelated seeds
+func DeriveSeed(seed int64, n int) int64 {
+	return int64(splitMix64(uint64(seed) + uint64(n+1)*0x9E3779B97F4A7C15))
+}
+
+//a SplitMix64 random source, which unlike the default source is essentially free to create and seed
+//this matters because every emulation creates its own source
+type splitMixSource struct {
+	state uint64
+}
+
+//the source is returned as well so its state can be saved and restored, see undo.go
+func newEmulationRand(seed int64) (*rand.Rand, *splitMixSource) {
+	src := &splitMixSource{state: uint64(seed)}
+	return rand.New(src), src
+}
+
+func (s *splitMixSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *splitMixSource) Uint64() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	return splitMix64(s.state)
+}
+
+func (s *splitMixSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
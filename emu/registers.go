@@ -0,0 +1,45 @@
+package emu
+
+import (
+	"fmt"
+	"strings"
+)
+
+//the standard O32 names of the registers, indexed by register number
+var registerNames = [32]string{
+	"zero", "at", "v0", "v1", "a0", "a1", "a2", "a3",
+	"t0", "t1", "t2", "t3", "t4", "t5", "t6", "t7",
+	"s0", "s1", "s2", "s3", "s4", "s5", "s6", "s7",
+	"t8", "t9", "k0", "k1", "gp", "sp", "fp", "ra",
+}
+
+//RegisterName returns the O32 name of the register, such as "$t0" for register 8
+func RegisterName(reg int) string {
+	if reg < 0 || reg > 31 {
+		return fmt.Sprintf("$%d", reg)
+	}
+
+	return "$" + registerNames[reg]
+}
+
+//FormatRegister describes the register by both its number and name, for example "$8 ($t0)"
+func FormatRegister(reg int) string {
+	return fmt.Sprintf("$%d (%s)", reg, RegisterName(reg))
+}
+
+//LookupRegister returns the number of the register with the given O32 name, with or without the leading '$'.
+//"s8" is accepted as an alias for "fp". Numeric registers are not accepted
+func LookupRegister(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "$"))
+	if name == "s8" {
+		return 30, true
+	}
+
+	for i, n := range registerNames {
+		if n == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
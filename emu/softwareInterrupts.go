@@ -0,0 +1,28 @@
+package emu
+
+import "fmt"
+
+//SoftwareInterrupt handles a swi instruction. Handlers are registered by the assignments that own them
+type SoftwareInterrupt func(m *Machine)
+
+var softwareInterrupts = make(map[int]SoftwareInterrupt)
+
+//RegisterSoftwareInterrupt makes the handler run for every swi with the given number. Should only be called from init
+//functions, as the table is shared by every emulation and is not guarded by a lock
+func RegisterSoftwareInterrupt(iCode int, handler SoftwareInterrupt) error {
+	if _, ok := softwareInterrupts[iCode]; ok {
+		return fmt.Errorf("swi %d already has a handler", iCode)
+	}
+
+	softwareInterrupts[iCode] = handler
+	return nil
+}
+
+func (inst *Machine) dispatchSoftwareInterrupt(iCode int) {
+	handler, ok := softwareInterrupts[iCode]
+	if !ok {
+		return
+	}
+
+	handler(inst)
+}
@@ -0,0 +1,218 @@
+package emu
+
+import (
+	"strconv"
+	"strings"
+)
+
+/**
+ * Syscall services
+ * The console services of SPIM (and the random number services of MARS), enabled with EnableSyscalls. The service is
+ * selected by $v0 and its arguments are in $a0 and $a1 ($f12 for printing floats), as in SPIM.
+ *
+ * Nothing is printed: the console output is captured in EmulationResult.Output, and the console input is a fixed
+ * string given when the services are enabled, so that every sample of a batch reads the same input and the emulation
+ * stays reproducible. The reads consume the input a line at a time, as SPIM does.
+ *
+ * Without the services, syscall is an exception (see cp0.go).
+ */
+
+//syscall service numbers, in $v0
+const (
+	SysPrintInt     = 1
+	SysPrintFloat   = 2
+	SysPrintDouble  = 3
+	SysPrintString  = 4
+	SysReadInt      = 5
+	SysReadFloat    = 6
+	SysReadDouble   = 7
+	SysReadString   = 8
+	SysSbrk         = 9
+	SysExit         = 10
+	SysPrintChar    = 11
+	SysReadChar     = 12
+	SysExit2        = 17
+	SysRandInt      = 41 //MARS
+	SysRandIntRange = 42 //MARS
+)
+
+const (
+	heapStart    = 0x10040000 //where sbrk allocates from, as in MARS
+	maxStringLen = 0x10000    //protects against printing a string that is never terminated
+)
+
+//EnableSyscalls turns on the syscall services, with reads consuming the input
+func (inst *Machine) EnableSyscalls(input string) {
+	inst.syscalls = true
+	inst.input = input
+	inst.inputPos = 0
+	inst.heapBreak = heapStart
+}
+
+//Output returns the console output of the syscall services so far
+func (inst *Machine) Output() string {
+	return inst.output.String()
+}
+
+//performs the service selected by $v0
+func (inst *Machine) executeSyscall() {
+	service := inst.RegAccess(2)
+	switch service {
+	case SysPrintInt:
+		inst.output.WriteString(strconv.Itoa(int(int32(inst.RegAccess(4)))))
+		break
+	case SysPrintFloat:
+		inst.output.WriteString(strconv.FormatFloat(inst.readFloat(12, false), 'g', -1, 32))
+		break
+	case SysPrintDouble:
+		inst.output.WriteString(strconv.FormatFloat(inst.readFloat(12, true), 'g', -1, 64))
+		break
+	case SysPrintString:
+		s, _ := inst.readString(inst.RegAccess(4))
+		inst.output.WriteString(s)
+		break
+	case SysPrintChar:
+		inst.output.WriteByte(byte(inst.RegAccess(4)))
+		break
+	case SysReadInt:
+		line, ok := inst.readLine("read_int")
+		if !ok {
+			inst.RegWrite(2, 0)
+			break
+		}
+		v, e := strconv.ParseInt(strings.TrimSpace(line), 10, 32)
+		if e != nil {
+			if ne, ok := e.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+				inst.ReportError(ESyscallInput, "read_int got \"%s\", which does not fit in 32 bits", strings.TrimSpace(line))
+			} else {
+				inst.ReportError(ESyscallInput, "read_int expected an integer, got \"%s\"", strings.TrimSpace(line))
+			}
+			v = 0
+		}
+		inst.RegWrite(2, uint32(v))
+		break
+	case SysReadFloat, SysReadDouble:
+		name := "read_float"
+		bitSize := 32
+		if service == SysReadDouble {
+			name = "read_double"
+			bitSize = 64
+		}
+		line, ok := inst.readLine(name)
+		v := 0.0
+		if ok {
+			var e error
+			v, e = strconv.ParseFloat(strings.TrimSpace(line), bitSize)
+			if e != nil {
+				inst.ReportError(ESyscallInput, "%s expected a number, got \"%s\"", name, strings.TrimSpace(line))
+				v = 0
+			}
+		}
+		inst.writeFloat(0, service == SysReadDouble, v) //the result is in $f0
+		break
+	case SysReadString:
+		inst.readStringService(inst.RegAccess(4), int32(inst.RegAccess(5)))
+		break
+	case SysReadChar:
+		if inst.inputPos >= len(inst.input) {
+			inst.ReportError(ESyscallInput, "read_char found no input left")
+			inst.RegWrite(2, 0)
+			break
+		}
+		inst.RegWrite(2, uint32(inst.input[inst.inputPos]))
+		inst.inputPos++
+		break
+	case SysSbrk:
+		n := int32(inst.RegAccess(4))
+		if n < 0 {
+			inst.ReportError(EInvalidSyscall, "sbrk cannot allocate a negative number of bytes (%d)", n)
+			break
+		}
+		inst.RegWrite(2, inst.heapBreak)
+		inst.heapBreak += (uint32(n) + 3) &^ 0x3 //keeping the heap word aligned
+		break
+	case SysExit:
+		inst.halted = true
+		break
+	case SysExit2:
+		inst.exitCode = int(int32(inst.RegAccess(4)))
+		inst.halted = true
+		break
+	case SysRandInt:
+		//$a0 is the generator, but every emulation has a single generator so that it is reproducible
+		inst.RegWrite(4, inst.rng.Uint32())
+		break
+	case SysRandIntRange:
+		upper := int32(inst.RegAccess(5))
+		if upper <= 0 {
+			inst.ReportError(EInvalidSyscall, "the upper bound of random_int_range must be positive, got %d", upper)
+			break
+		}
+		inst.RegWrite(4, uint32(inst.rng.Int31n(upper)))
+		break
+	default:
+		inst.ReportError(EInvalidSyscall, "%d in $v0 is not a supported syscall service", int32(service))
+	}
+}
+
+//reads the null-terminated string at the address, returns false if it could not be read to its end
+func (inst *Machine) readString(addr uint32) (string, bool) {
+	var sb strings.Builder
+	for i := 0; maxStringLen > i; i++ {
+		a := addr + uint32(i)
+		w, ok := inst.memAccess(a, false)
+		if !ok {
+			return sb.String(), false
+		}
+		b := byte(w >> ((a % 4) * 8))
+		if b == 0 {
+			return sb.String(), true
+		}
+		sb.WriteByte(b)
+	}
+
+	inst.ReportError(EInvalidSyscall, "the string at 0x%X is not terminated within %d bytes", addr, maxStringLen)
+	return sb.String(), false
+}
+
+//consumes the next line of input, including its newline. Returns false if there is no input left
+func (inst *Machine) readLine(name string) (string, bool) {
+	if inst.inputPos >= len(inst.input) {
+		inst.ReportError(ESyscallInput, "%s found no input left", name)
+		return "", false
+	}
+
+	rest := inst.input[inst.inputPos:]
+	end := strings.IndexByte(rest, '\n')
+	if end == -1 {
+		end = len(rest)
+	} else {
+		end++
+	}
+	inst.inputPos += end
+	return rest[:end], true
+}
+
+//reads at most n-1 characters of the line into the buffer and terminates it, like fgets
+func (inst *Machine) readStringService(buf uint32, n int32) {
+	if n < 1 {
+		return
+	}
+
+	line, _ := inst.readLine("read_string")
+	if len(line) > int(n-1) {
+		//the rest of the line is left for the next read
+		inst.inputPos -= len(line) - int(n-1)
+		line = line[:n-1]
+	}
+
+	for i := 0; len(line)+1 > i; i++ {
+		var b uint32 //the terminator after the line
+		if i < len(line) {
+			b = uint32(line[i])
+		}
+		a := buf + uint32(i)
+		inst.MemWrite(a, b<<((a%4)*8), 0xFF<<((a%4)*8))
+	}
+}
+
@@ -0,0 +1,190 @@
+package emu
+
+import "strings"
+
+/**
+ * Keyboard and display
+ * The memory-mapped terminal of MARS (the "Keyboard and Display MMIO Simulator"), with the same registers:
+ *  - 0xFFFF0000 receiver control, bit 0 is set when a key is ready and bit 1 enables interrupts
+ *  - 0xFFFF0004 receiver data, reading it takes the key and clears the ready bit
+ *  - 0xFFFF0008 transmitter control, bit 0 is set when the display is ready and bit 1 enables interrupts
+ *  - 0xFFFF000C transmitter data, writing the low byte displays the character
+ *
+ * The keys are scripted rather than typed: the next key becomes ready a fixed number of instructions after the
+ * previous one was read, and the display is busy for a fixed number of instructions after every character, so that
+ * polling loops behave as they do in MARS while the emulation stays reproducible. The interrupt enable bits are kept
+ * but interrupts are not raised.
+ */
+
+//the addresses of the terminal registers
+const (
+	KeyboardControl = 0xFFFF0000
+	KeyboardData    = 0xFFFF0004
+	DisplayControl  = 0xFFFF0008
+	DisplayData     = 0xFFFF000C
+)
+
+//DefaultTerminalDelay is the number of instructions the display is busy for, the default of MARS
+const DefaultTerminalDelay = 5
+
+const (
+	mmioReady           = 0x1
+	mmioInterruptEnable = 0x2
+)
+
+//Keyboard is the receiver of the MARS terminal, typing the scripted keys
+type Keyboard struct {
+	keys      string
+	next      int
+	last      uint32 //the last key read, which the data register keeps
+	delay     uint32
+	readyAt   uint32
+	interrupt bool
+}
+
+//NewKeyboard creates a keyboard that types the keys, each one becoming ready delay instructions after the last was read
+func NewKeyboard(keys string, delay uint32) *Keyboard {
+	return &Keyboard{
+		keys:    keys,
+		delay:   delay,
+		readyAt: delay,
+	}
+}
+
+//Range returns the receiver registers
+func (k *Keyboard) Range() (uint32, uint32) {
+	return KeyboardControl, KeyboardData + 3
+}
+
+func (k *Keyboard) ready(inst *Machine) bool {
+	return k.next < len(k.keys) && inst.DI() >= k.readyAt
+}
+
+//Read takes the key when reading the data register
+func (k *Keyboard) Read(inst *Machine, addr uint32) uint32 {
+	if addr == KeyboardData {
+		if k.ready(inst) {
+			k.last = uint32(k.keys[k.next])
+			k.next++
+			k.readyAt = inst.DI() + k.delay
+		}
+		return k.last
+	}
+
+	var v uint32
+	if k.ready(inst) {
+		v |= mmioReady
+	}
+	if k.interrupt {
+		v |= mmioInterruptEnable
+	}
+	return v
+}
+
+//Write sets the interrupt enable bit, the data register is read only
+func (k *Keyboard) Write(inst *Machine, addr, data, mask uint32) {
+	if addr == KeyboardControl && mask&mmioInterruptEnable != 0 {
+		//only the interrupt enable bit is writable
+		k.interrupt = data&mmioInterruptEnable != 0
+	}
+}
+
+//SaveState returns the keyboard's state, which is small enough to be copied whole
+func (k *Keyboard) SaveState(addr uint32) interface{} {
+	return *k
+}
+
+//RestoreState restores the state returned by SaveState
+func (k *Keyboard) RestoreState(state interface{}) {
+	*k = state.(Keyboard)
+}
+
+//Remaining returns the number of keys that have not been read
+func (k *Keyboard) Remaining() int {
+	return len(k.keys) - k.next
+}
+
+//the state of the display that an access can change, the output is restored by truncating it
+type displayState struct {
+	outputLen int
+	busyUntil uint32
+	interrupt bool
+}
+
+//Display is the transmitter of the MARS terminal
+type Display struct {
+	output    strings.Builder
+	delay     uint32
+	busyUntil uint32
+	interrupt bool
+}
+
+//NewDisplay creates a display that is busy for delay instructions after every character
+func NewDisplay(delay uint32) *Display {
+	return &Display{delay: delay}
+}
+
+//Range returns the transmitter registers
+func (d *Display) Range() (uint32, uint32) {
+	return DisplayControl, DisplayData + 3
+}
+
+//Read returns whether the display is ready
+func (d *Display) Read(inst *Machine, addr uint32) uint32 {
+	if addr == DisplayData {
+		return 0
+	}
+
+	var v uint32
+	if inst.DI() >= d.busyUntil {
+		v |= mmioReady
+	}
+	if d.interrupt {
+		v |= mmioInterruptEnable
+	}
+	return v
+}
+
+//Write displays the character when writing the data register
+func (d *Display) Write(inst *Machine, addr, data, mask uint32) {
+	if addr == DisplayControl {
+		if mask&mmioInterruptEnable != 0 {
+			d.interrupt = data&mmioInterruptEnable != 0
+		}
+		return
+	}
+
+	if mask&0xFF == 0 {
+		//the character is the low byte
+		return
+	}
+	if inst.DI() < d.busyUntil {
+		inst.ReportError(EDeviceAccess, "the display was written to before it was ready, the character 0x%X is lost",
+			data&0xFF)
+		return
+	}
+	d.output.WriteByte(byte(data))
+	d.busyUntil = inst.DI() + d.delay
+}
+
+//SaveState returns the display's state
+func (d *Display) SaveState(addr uint32) interface{} {
+	return displayState{outputLen: d.output.Len(), busyUntil: d.busyUntil, interrupt: d.interrupt}
+}
+
+//RestoreState restores the state returned by SaveState
+func (d *Display) RestoreState(state interface{}) {
+	s := state.(displayState)
+	if d.output.Len() != s.outputLen {
+		out := d.output.String()[:s.outputLen]
+		d.output.Reset()
+		d.output.WriteString(out)
+	}
+	d.busyUntil = s.busyUntil
+	d.interrupt = s.interrupt
+}
+
+//Output returns the characters displayed so far
+func (d *Display) Output() string {
+	return d.output.String()
+}
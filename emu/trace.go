@@ -0,0 +1,105 @@
+package emu
+
+/**
+ * Instruction trace
+ * An optional ring buffer of the last instructions executed, enabled with EnableTrace, so the steps that led to the
+ * final state of a snapshot can be seen. Every entry holds the instruction, the register it wrote and the memory it
+ * touched, the source line is joined in later from the assembler's line metadata (see vet.WriteTrace).
+ */
+
+//the kind of memory access of a traced instruction
+const (
+	TraceNoAccess = iota
+	TraceLoad
+	TraceStore
+)
+
+//TraceEntry is an executed instruction
+type TraceEntry struct {
+	DI          uint32 //the instruction count before it was executed
+	PC          uint32
+	Instruction uint32
+	Reg         int  //the register written, -1 if none
+	FPReg       bool //whether Reg is a floating-point register
+	RegValue    uint32
+	Access      int    //TraceNoAccess, TraceLoad or TraceStore, the last one if there are several (ldc1, sdc1)
+	MemAddr     uint32 //the address given by the instruction
+	MemValue    uint32 //the word read, or the bits written within the word
+}
+
+//Disassemble returns the assembly of the instruction
+func (e TraceEntry) Disassemble() string {
+	return Disassemble(e.Instruction, e.PC)
+}
+
+//RegName returns the name of the register written, blank if none
+func (e TraceEntry) RegName() string {
+	if e.Reg < 0 {
+		return ""
+	} else if e.FPReg {
+		return FormatFPRegister(e.Reg)
+	}
+
+	return RegisterName(e.Reg)
+}
+
+//EnableTrace keeps the last size instructions executed, see EmulationResult.Trace. Not enabled by default as it
+//slows the emulation down
+func (inst *Machine) EnableTrace(size int) {
+	if size <= 0 {
+		inst.trace = nil
+		return
+	}
+
+	inst.trace = make([]TraceEntry, size)
+	inst.traceLen = 0
+	inst.traceNext = 0
+}
+
+//starts the entry of the instruction at the pc, overwriting the oldest entry once the buffer is full
+func (inst *Machine) beginTrace() {
+	e := &inst.trace[inst.traceNext]
+	*e = TraceEntry{
+		DI:  inst.di,
+		PC:  inst.pc,
+		Reg: -1,
+	}
+	inst.traced = e
+
+	inst.traceNext = (inst.traceNext + 1) % len(inst.trace)
+	if inst.traceLen < len(inst.trace) {
+		inst.traceLen++
+	}
+}
+
+//records a register write in the entry of the instruction being executed
+func (inst *Machine) traceReg(reg int, fp bool, value uint32) {
+	if inst.traced != nil {
+		inst.traced.Reg = reg
+		inst.traced.FPReg = fp
+		inst.traced.RegValue = value
+	}
+}
+
+//records a memory access in the entry of the instruction being executed
+func (inst *Machine) traceMemory(access int, addr, value uint32) {
+	if inst.traced != nil {
+		inst.traced.Access = access
+		inst.traced.MemAddr = addr
+		inst.traced.MemValue = value
+	}
+}
+
+//returns the traced instructions from the oldest, nil if the trace is not enabled
+func (inst *Machine) traceEntries() []TraceEntry {
+	if inst.trace == nil {
+		return nil
+	}
+
+	ret := make([]TraceEntry, 0, inst.traceLen)
+	if inst.traceLen < len(inst.trace) {
+		return append(ret, inst.trace[:inst.traceLen]...)
+	}
+	ret = append(ret, inst.trace[inst.traceNext:]...)
+	return append(ret, inst.trace[:inst.traceNext]...)
+}
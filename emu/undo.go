@@ -0,0 +1,302 @@
+package emu
+
+/**
+ * Undo log
+ * An optional history of the executed instructions, enabled with EnableUndo, that lets a debugger execute the program
+ * backwards. Before every instruction, the small state that is not written through RegWrite, FPRegWrite or MemWrite is
+ * saved (the pc, hi and lo, the syscall services...), and every register, memory and coprocessor 0 write logs the value
+ * it overwrote, as do the cache misses, so Undo can restore the state before the last instruction.
+ *
+ * The state of the software interrupts (including the SWI context) and of the memory-mapped devices lives outside of the
+ * machine. Devices that implement UndoableDevice save the state an access changes, as the built-in devices do, but the
+ * instructions that reach a software interrupt or another device cannot be undone. Undo refuses them and leaves it to
+ * the caller to emulate again from the start, which gives the same result as the emulation is deterministic.
+ *
+ * The log also answers which instruction last wrote a register or a word of memory (see LastRegWrite).
+ * The profile, call profile and trace are not rewound.
+ */
+
+//UndoWrite is a register or memory write found in the undo log
+type UndoWrite struct {
+	DI  uint32 //the instruction count of the instruction that wrote, before it was executed
+	PC  uint32
+	Old uint32 //the value before the write
+	New uint32
+}
+
+type regUndo struct {
+	reg      int
+	fp       bool
+	old, new uint32
+}
+
+type memUndo struct {
+	addr     uint32 //word aligned
+	old, new uint32
+	wasInit  bool
+	newPage  bool //the write created the page, which is removed when undone
+}
+
+type cp0Undo struct {
+	reg int
+	old uint32
+}
+
+//the state of a device before an access
+type deviceUndo struct {
+	device UndoableDevice
+	state  interface{}
+}
+
+//the caches before a miss replaced them
+type cacheUndo struct {
+	iCache MemoryPage
+	dCache MemoryPage
+}
+
+//the state before an instruction, and the writes it made
+type undoRecord struct {
+	pc, di       uint32
+	hi, lo       uint32
+	regInit      uint32
+	fprInit      uint32
+	heapBreak    uint32
+	numErrors    int
+	inputPos     int
+	outputLen    int
+	exitCode     int
+	rngState     uint64
+	fcc          uint8
+	halted       bool
+	hiLoFilled   bool
+	dMissed      bool
+	branchPC     uint32
+	branchInfo   BranchInfo
+	branched     bool //whether the instruction was a conditional branch, which updates branchPC's analysis
+	branchNew    bool //whether it was the branch's first execution, which adds it to the analysis
+	regs         []regUndo
+	mem          []memUndo
+	cp0          []cp0Undo
+	devices      []deviceUndo
+	caches       *cacheUndo //nil unless the instruction missed a cache
+	irreversible bool       //the instruction reached a software interrupt or a device that cannot be undone
+}
+
+//EnableUndo logs every instruction executed from now on so it can be undone, see Undo. Not enabled by default as the
+//log grows with every instruction
+func (inst *Machine) EnableUndo() {
+	inst.undoing = true
+	inst.history = nil
+}
+
+//UndoLen returns the number of instructions in the undo log
+func (inst *Machine) UndoLen() int {
+	return len(inst.history)
+}
+
+//saves the state before the instruction at the pc
+func (inst *Machine) beginUndo() {
+	inst.history = append(inst.history, undoRecord{
+		pc:         inst.pc,
+		di:         inst.di,
+		hi:         inst.hi,
+		lo:         inst.lo,
+		regInit:    inst.regInit,
+		fprInit:    inst.fprInit,
+		heapBreak:  inst.heapBreak,
+		numErrors:  len(inst.errors),
+		inputPos:   inst.inputPos,
+		outputLen:  inst.output.Len(),
+		exitCode:   inst.exitCode,
+		rngState:   inst.rngSource.state,
+		fcc:        inst.fcc,
+		halted:     inst.halted,
+		hiLoFilled: inst.hiLoFilled,
+		dMissed:    inst.dMissed,
+	})
+}
+
+//the record of the instruction being executed, nil before the first one
+func (inst *Machine) undoRecord() *undoRecord {
+	if len(inst.history) == 0 {
+		return nil
+	}
+
+	return &inst.history[len(inst.history)-1]
+}
+
+//logs a register write, before it is made
+func (inst *Machine) logRegWrite(reg int, fp bool, data uint32) {
+	r := inst.undoRecord()
+	if r == nil {
+		return
+	}
+	old := inst.regs[reg]
+	if fp {
+		old = inst.fpr[reg]
+	}
+	r.regs = append(r.regs, regUndo{reg: reg, fp: fp, old: old, new: data})
+}
+
+//logs a memory write to the word, before it is made
+func (inst *Machine) logMemWrite(addr, data, mask uint32) {
+	r := inst.undoRecord()
+	if r == nil {
+		return
+	}
+	addr &= 0xFFFFFFFC
+	page, ok := inst.memory[addr>>12]
+	u := memUndo{addr: addr, newPage: !ok}
+	if ok {
+		u.old = page.Memory[addr/4%1024]
+		u.wasInit = (page.Initialized[(addr%4096)/128]>>((addr%4096)/4%32))&0x1 == 0x1
+	}
+	u.new = data&mask | u.old&^mask
+	r.mem = append(r.mem, u)
+}
+
+//logs a coprocessor 0 write, before it is made
+func (inst *Machine) logCP0Write(reg int) {
+	if r := inst.undoRecord(); r != nil {
+		r.cp0 = append(r.cp0, cp0Undo{reg: reg, old: inst.cp0[reg]})
+	}
+}
+
+//logs the caches before a miss replaces one of them, only the first miss of the instruction is kept
+func (inst *Machine) logCacheMiss() {
+	if r := inst.undoRecord(); r != nil && r.caches == nil {
+		r.caches = &cacheUndo{iCache: inst.iCache, dCache: inst.dCache}
+	}
+}
+
+//logs the branch analysis of a conditional branch, before it is updated
+func (inst *Machine) logBranch() {
+	r := inst.undoRecord()
+	if r == nil {
+		return
+	}
+	r.branchPC = inst.pc
+	info, ok := inst.branchInfo[inst.pc]
+	r.branchInfo = info
+	r.branched = true
+	r.branchNew = !ok
+}
+
+//logs the state of the device before it is accessed, or marks the instruction as one that cannot be undone if the
+//device cannot save its state
+func (inst *Machine) logDevice(d Device, addr uint32) {
+	r := inst.undoRecord()
+	if r == nil {
+		return
+	}
+	u, ok := d.(UndoableDevice)
+	if !ok {
+		r.irreversible = true
+		return
+	}
+	r.devices = append(r.devices, deviceUndo{device: u, state: u.SaveState(addr)})
+}
+
+//marks the instruction being executed as one that cannot be undone
+func (inst *Machine) logIrreversible() {
+	if r := inst.undoRecord(); r != nil {
+		r.irreversible = true
+	}
+}
+
+//Undo restores the state before the last instruction executed, and returns false if there is no instruction to undo
+//or the last one reached a software interrupt or a device that cannot be undone. In that case, the state can be recovered by emulating
+//again from the start up to UndoLen()-1 instructions
+func (inst *Machine) Undo() bool {
+	if len(inst.history) == 0 || inst.history[len(inst.history)-1].irreversible {
+		return false
+	}
+
+	r := &inst.history[len(inst.history)-1]
+	for i := len(r.mem) - 1; i >= 0; i-- {
+		u := r.mem[i]
+		if u.newPage {
+			delete(inst.memory, u.addr>>12)
+			continue
+		}
+		page := inst.memory[u.addr>>12]
+		page.Memory[u.addr/4%1024] = u.old
+		if !u.wasInit {
+			page.Initialized[(u.addr%4096)/128] &^= 0x1 << ((u.addr % 4096) / 4 % 32)
+		}
+	}
+	for i := len(r.regs) - 1; i >= 0; i-- {
+		u := r.regs[i]
+		if u.fp {
+			inst.fpr[u.reg] = u.old
+		} else {
+			inst.regs[u.reg] = u.old
+		}
+	}
+	for i := len(r.devices) - 1; i >= 0; i-- {
+		r.devices[i].device.RestoreState(r.devices[i].state)
+	}
+	for i := len(r.cp0) - 1; i >= 0; i-- {
+		inst.cp0[r.cp0[i].reg] = r.cp0[i].old
+	}
+	if r.caches != nil {
+		inst.iCache, inst.dCache = r.caches.iCache, r.caches.dCache
+	}
+	if r.branchNew {
+		delete(inst.branchInfo, r.branchPC)
+	} else if r.branched {
+		inst.branchInfo[r.branchPC] = r.branchInfo
+	}
+
+	inst.pc = r.pc
+	inst.di = r.di
+	inst.halted = r.halted
+	inst.hi, inst.lo, inst.hiLoFilled = r.hi, r.lo, r.hiLoFilled
+	inst.regInit = r.regInit
+	inst.fprInit = r.fprInit
+	inst.fcc = r.fcc
+	inst.dMissed = r.dMissed
+	inst.errors = inst.errors[:r.numErrors]
+	inst.inputPos = r.inputPos
+	if inst.output.Len() != r.outputLen {
+		out := inst.output.String()[:r.outputLen]
+		inst.output.Reset()
+		inst.output.WriteString(out)
+	}
+	inst.heapBreak = r.heapBreak
+	inst.exitCode = r.exitCode
+	inst.rngSource.state = r.rngState
+
+	inst.history = inst.history[:len(inst.history)-1]
+	return true
+}
+
+//LastRegWrite returns the last write to the register in the undo log, and false if there is none
+func (inst *Machine) LastRegWrite(reg int, fp bool) (UndoWrite, bool) {
+	for i := len(inst.history) - 1; i >= 0; i-- {
+		r := &inst.history[i]
+		for j := len(r.regs) - 1; j >= 0; j-- {
+			if u := r.regs[j]; u.reg == reg && u.fp == fp {
+				return UndoWrite{DI: r.di, PC: r.pc, Old: u.old, New: u.new}, true
+			}
+		}
+	}
+
+	return UndoWrite{}, false
+}
+
+//LastMemWrite returns the last write to the word at the address in the undo log, and false if there is none. Writes
+//to devices are not logged
+func (inst *Machine) LastMemWrite(addr uint32) (UndoWrite, bool) {
+	addr &= 0xFFFFFFFC
+	for i := len(inst.history) - 1; i >= 0; i-- {
+		r := &inst.history[i]
+		for j := len(r.mem) - 1; j >= 0; j-- {
+			if u := r.mem[j]; u.addr == addr {
+				return UndoWrite{DI: r.di, PC: r.pc, Old: u.old, New: u.new}, true
+			}
+		}
+	}
+
+	return UndoWrite{}, false
+}
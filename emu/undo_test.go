@@ -0,0 +1,204 @@
+package emu
+
+import (
+	"reflect"
+	"testing"
+)
+
+//creates a machine running the instructions from address 0, logging them for Undo
+func newUndoMachine(instructions ...uint32) *Machine {
+	mem := make(SystemMemory)
+	mem.AddImage(&MemoryImage{StartingAddr: 0, Memory: instructions})
+	inst := New(0, mem, 1000, 5, 1)
+	inst.EnableSyscalls("")
+	inst.EnableUndo()
+	return inst
+}
+
+//the state Undo restores, with a copy of the memory as the result shares it with the machine
+func undoState(inst *Machine) EmulationResult {
+	res := inst.Result()
+	res.Memory = res.Memory.Clone()
+	res.BranchAnalysis = make(map[uint32]BranchInfo)
+	for k, v := range inst.branchInfo {
+		res.BranchAnalysis[k] = v
+	}
+	res.Devices = nil //compared by the device tests
+	res.Errors = append([]RuntimeError{}, res.Errors...)
+	return res
+}
+
+func TestUndo(t *testing.T) {
+	program := []uint32{
+		FormIInstruction(OpADDIU, 0, 8, 3),           //addiu $t0, $0, 3
+		FormIInstruction(OpLUI, 0, 9, 0x1001),        //lui $t1, 0x1001
+		FormIInstruction(OpSW, 9, 8, 0),              //loop: sw $t0, 0($t1), creating the page
+		FormRInstruction(OpMULT, 8, 8, 0, 0, FnMULT), //mult $t0, $t0
+		FormIInstruction(OpADDIU, 8, 8, 0xFFFF),      //addiu $t0, $t0, -1
+		FormIInstruction(OpBNE, 8, 0, 0xFFFC),        //bne $t0, $0, loop
+		FormIInstruction(OpADDIU, 0, 2, 11),          //addiu $v0, $0, 11
+		FormIInstruction(OpADDIU, 0, 4, 'A'),         //addiu $a0, $0, 'A'
+		FormRInstruction(0, 0, 0, 0, 0, FnSYSCALL),   //syscall, printing the character
+		FormIInstruction(OpLW, 0, 10, 0xFFF0),        //lw $t2, -16($0), an uninitialized word
+		FormRInstruction(OpJR, 31, 0, 0, 0, FnJR),    //jr $ra, ending the emulation
+	}
+
+	//the state before every instruction
+	inst := newUndoMachine(program...)
+	var states []EmulationResult
+	for !inst.Halted() {
+		states = append(states, undoState(inst))
+		inst.Step()
+	}
+	end := undoState(inst)
+	if end.Output != "A" || len(end.Errors) != 1 || end.HiLoFilled != true || len(end.BranchAnalysis) != 1 {
+		t.Fatalf("the program did not run as expected: output %q, errors %v", end.Output, end.Errors)
+	}
+	if inst.UndoLen() != len(states) {
+		t.Fatalf("%d instructions were logged, want %d", inst.UndoLen(), len(states))
+	}
+
+	for i := len(states) - 1; i >= 0; i-- {
+		if !inst.Undo() {
+			t.Fatalf("could not undo instruction %d", i)
+		}
+		if got := undoState(inst); !reflect.DeepEqual(got, states[i]) {
+			t.Fatalf("the state after undoing instruction %d differs:\ngot  %+v\nwant %+v", i, got, states[i])
+		}
+	}
+	if inst.Undo() {
+		t.Error("undid an instruction before the start of the program")
+	}
+
+	//emulating again after undoing gives the same result
+	for !inst.Halted() {
+		inst.Step()
+	}
+	if got := undoState(inst); !reflect.DeepEqual(got, end) {
+		t.Errorf("the state after emulating again differs:\ngot  %+v\nwant %+v", got, end)
+	}
+}
+
+func TestUndoExceptions(t *testing.T) {
+	program := []uint32{
+		FormIInstruction(OpLUI, 0, 8, 0x7FFF),        //lui $t0, 0x7FFF
+		FormRInstruction(OpADD, 8, 8, 9, 0, FnADD),   //add $t1, $t0, $t0, overflowing to the handler
+		FormRInstruction(OpJR, 31, 0, 0, 0, FnJR),    //jr $ra
+		FormIInstruction(OpCOP0, RsMFC0, 10, 14<<11), //handler: mfc0 $t2, $14
+		FormIInstruction(OpADDIU, 10, 10, 4),         //addiu $t2, $t2, 4
+		FormIInstruction(OpCOP0, RsMTC0, 10, 14<<11), //mtc0 $t2, $14
+		FormIInstruction(OpCOP0, RsCO, 0, FnERET),    //eret
+	}
+
+	inst := newUndoMachine(program...)
+	inst.EnableExceptions(12)
+	var states []EmulationResult
+	for !inst.Halted() {
+		states = append(states, undoState(inst))
+		inst.Step()
+	}
+
+	//the handler moved EPC past the add at 4
+	if res := inst.Result(); res.CP0[CP0EPC] != 8 || res.CP0[CP0Cause]>>2&0x1F != ExcOv {
+		t.Fatalf("the exception was not handled: EPC 0x%X, Cause 0x%X", res.CP0[CP0EPC], res.CP0[CP0Cause])
+	}
+
+	for i := len(states) - 1; i >= 0; i-- {
+		if !inst.Undo() {
+			t.Fatalf("could not undo instruction %d", i)
+		}
+		if got := undoState(inst); !reflect.DeepEqual(got, states[i]) {
+			t.Fatalf("the state after undoing instruction %d differs:\ngot  %+v\nwant %+v", i, got, states[i])
+		}
+	}
+}
+
+//a device that cannot save its state
+type counterDevice struct {
+	reads uint32
+}
+
+func (c *counterDevice) Range() (uint32, uint32) {
+	return 0xFFFF1000, 0xFFFF1003
+}
+
+func (c *counterDevice) Read(inst *Machine, addr uint32) uint32 {
+	c.reads++
+	return c.reads
+}
+
+func (c *counterDevice) Write(inst *Machine, addr, data, mask uint32) {
+}
+
+func TestUndoIrreversible(t *testing.T) {
+	inst := newUndoMachine(
+		FormIInstruction(OpADDIU, 0, 8, 1),    //addiu $t0, $0, 1
+		FormIInstruction(OpSWI, 0, 0, 900),    //swi 900
+		FormIInstruction(OpLUI, 0, 9, 0xFFFF), //lui $t1, 0xFFFF
+		FormIInstruction(OpLW, 9, 10, 0x1000), //lw $t2, 0x1000($t1), from the counter
+		FormIInstruction(OpADDIU, 0, 8, 2),    //addiu $t0, $0, 2
+	)
+	if e := inst.AttachDevice(&counterDevice{}); e != nil {
+		t.Fatal(e)
+	}
+	for i := 0; 5 > i; i++ {
+		inst.Step()
+	}
+
+	if !inst.Undo() {
+		t.Fatal("could not undo the addiu after the device access")
+	}
+	if inst.Undo() {
+		t.Error("undid the load from a device that cannot save its state")
+	}
+	if inst.UndoLen() != 4 || inst.PC() != 0x10 {
+		t.Errorf("the failed undo changed the state: %d instructions logged, pc 0x%X", inst.UndoLen(), inst.PC())
+	}
+
+	//a software interrupt
+	inst = newUndoMachine(FormIInstruction(OpADDIU, 0, 8, 1), FormIInstruction(OpSWI, 0, 0, 900))
+	inst.Step()
+	inst.Step()
+	if inst.Undo() {
+		t.Error("undid a software interrupt")
+	}
+}
+
+func TestUndoDevices(t *testing.T) {
+	inst := newUndoMachine(
+		FormIInstruction(OpLUI, 0, 8, 0xFFFF),  //lui $t0, 0xFFFF
+		FormIInstruction(OpLBU, 8, 9, 4),       //lbu $t1, 4($t0), taking the key
+		FormIInstruction(OpSB, 8, 9, 12),       //sb $t1, 12($t0), displaying it
+		FormIInstruction(OpLUI, 0, 10, 0x1001), //lui $t2, 0x1001
+		FormIInstruction(OpSW, 10, 9, 0),       //sw $t1, 0($t2), a pixel
+	)
+	keyboard := NewKeyboard("x", 0)
+	display := NewDisplay(5)
+	fb, e := NewFramebuffer(FramebufferSettings{Base: 0x10010000, Width: 2, Height: 2, FrameInterval: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, d := range []Device{keyboard, display, fb} {
+		if e := inst.AttachDevice(d); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	for i := 0; 5 > i; i++ {
+		inst.Step()
+	}
+	if display.Output() != "x" || keyboard.Remaining() != 0 || fb.words[0] != 'x' || len(fb.frames) != 1 {
+		t.Fatalf("the devices were not accessed as expected: output %q, %d keys remaining, pixel 0x%X, %d frames",
+			display.Output(), keyboard.Remaining(), fb.words[0], len(fb.frames))
+	}
+
+	for inst.UndoLen() > 0 {
+		if !inst.Undo() {
+			t.Fatalf("could not undo instruction %d", inst.UndoLen()-1)
+		}
+	}
+	if display.Output() != "" || keyboard.Remaining() != 1 || fb.words[0] != 0 || len(fb.frames) != 0 {
+		t.Errorf("the devices were not restored: output %q, %d keys remaining, pixel 0x%X, %d frames",
+			display.Output(), keyboard.Remaining(), fb.words[0], len(fb.frames))
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"time"
 )
 
-func generateEula(reader *bufio.Reader) {
+func buildEulaText() string {
 	builder := strings.Builder{}
 
 	builder.WriteString("MIPSVet Eula\n")
@@ -27,7 +27,13 @@ func generateEula(reader *bufio.Reader) {
 		" of this software is not responsible for what you do with the software or the source code.\n")
 	builder.WriteString("eula=false")
 
-	e := ioutil.WriteFile("eula.txt", []byte(builder.String()), 0644)
+	return builder.String()
+}
+
+func generateEula(reader *bufio.Reader) {
+	eulaText := buildEulaText()
+
+	e := ioutil.WriteFile("eula.txt", []byte(eulaText), 0644)
 	if e != nil {
 		fmt.Println("Error generating eula file:", e.Error())
 		time.Sleep(4 * time.Second)
@@ -36,8 +42,9 @@ func generateEula(reader *bufio.Reader) {
 
 	fmt.Println("+===[ IMPORTANT ]===+")
 	fmt.Println("An EULA (End User Licence Agreement) has been generated in the directory of the executable.")
-	fmt.Println("To use the software, please agree to the EULA. For convenience, the EULA is repeated here:\n")
-	fmt.Println(builder.String())
+	fmt.Println("To use the software, please agree to the EULA. For convenience, the EULA is repeated here:")
+	fmt.Println()
+	fmt.Println(eulaText)
 	fmt.Println("\nTo agree to the EULA, either edit the file and restart the program, or type 'I agree' below.")
 	statement, _ := reader.ReadString('\n')
 	statement = strings.Trim(statement, " \n\t\r")
@@ -47,8 +54,7 @@ func generateEula(reader *bufio.Reader) {
 		os.Exit(3)
 	}
 
-	fContents := builder.String()
-	fContents = strings.Replace(fContents, "eula=false", "eula=true", 1)
+	fContents := strings.Replace(eulaText, "eula=false", "eula=true", 1)
 
 	e = ioutil.WriteFile("eula.txt", []byte(fContents), 0644)
 	if e != nil {
@@ -106,3 +112,31 @@ func validateEula(reader *bufio.Reader) {
 		os.Exit(3)
 	}
 }
+
+//non-interactive variant of validateEula for scripted use; never reads from stdin
+//returns true if the EULA has been (or was just) agreed to
+func validateEulaNonInteractive(agree bool) bool {
+	fContentsB, e := ioutil.ReadFile("eula.txt")
+	if e == nil && strings.Contains(string(fContentsB), "eula=true") {
+		//eula validated
+		return true
+	} else if e != nil && !os.IsNotExist(e) {
+		fmt.Println("Error reading eula file:", e.Error())
+		return false
+	}
+
+	if !agree {
+		fmt.Println("The EULA has not been agreed to. Either run the program without arguments to agree to it " +
+			"interactively, or pass -agree-eula to agree to the EULA in eula.txt.")
+		return false
+	}
+
+	fContents := strings.Replace(buildEulaText(), "eula=false", "eula=true", 1)
+	e = ioutil.WriteFile("eula.txt", []byte(fContents), 0644)
+	if e != nil {
+		fmt.Println("Error updating eula file:", e.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,534 @@
+package explorer
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/vet"
+)
+
+/**
+ * Debugger
+ * Live debugging of a snapshot from within the explorer. Because every emulation is reproducible from its seed, the
+ * debugger emulates the snapshot's sample again from the assembled memory, one instruction at a time.
+ *
+ * The debugger keeps a shadow call depth (incremented by calls such as jal and decremented by jr $31) so that 'next' can
+ * step over function calls and 'finish' can run until the current function returns.
+ */
+
+//Program is the assembled program and emulation settings the snapshots were created with, which lets the explorer
+//emulate a snapshot again in the debugger
+type Program struct {
+	Memory     emu.SystemMemory //the assembled memory, is cloned for every debug session
+	StartAddr  uint32
+	Limit      uint32
+	ETol       int
+	ISA        emu.ISA
+	Exceptions bool
+	Handler    uint32
+	Syscalls   bool
+	Input      string              //the console input of the syscall services
+	Devices    func() []emu.Device //creates the memory-mapped devices, may be nil
+	FileName   string
+	Source     string           //the assembly source, for the annotated listing of the profile
+	Profile    *vet.Profile     //the profile of the batch, nil if it was not profiled
+	Calls      *vet.CallProfile //the call profile of the batch, nil if the calls were not profiled
+	Coverage   *vet.Coverage    //the code coverage of the batch, nil if it was not measured
+}
+
+type debugWatch struct {
+	value       uint32
+	initialized bool
+}
+
+type debugger struct {
+	inst        *emu.Machine
+	program     *Program
+	labels      map[string]uint32
+	lineMeta    map[uint32]asm.InputLine
+	breakpoints map[uint32]bool
+	watches     map[uint32]debugWatch
+	depth       int   //the shadow call depth
+	depths      []int //the depth before every instruction in the undo log, to restore it when stepping back
+}
+
+type debugRegState struct {
+	regs       [32]uint32
+	regInit    uint32
+	hi, lo     uint32
+	hiLoFilled bool
+	fpRegs     [32]uint32
+	fpRegInit  uint32
+}
+
+func newDebugger(program *Program, seed int64, labels map[string]uint32, lineMeta map[uint32]asm.InputLine) *debugger {
+	d := &debugger{
+		program:     program,
+		labels:      labels,
+		lineMeta:    lineMeta,
+		breakpoints: make(map[uint32]bool),
+		watches:     make(map[uint32]debugWatch),
+	}
+	d.restart(seed)
+	return d
+}
+
+func (d *debugger) restart(seed int64) {
+	d.inst = emu.New(d.program.StartAddr, d.program.Memory.Clone(), d.program.Limit, d.program.ETol, seed)
+	d.inst.SetISA(d.program.ISA)
+	d.inst.EnableUndo()
+	if d.program.Exceptions {
+		d.inst.EnableExceptions(d.program.Handler)
+	}
+	if d.program.Syscalls {
+		d.inst.EnableSyscalls(d.program.Input)
+	}
+	if d.program.Devices != nil {
+		for _, dev := range d.program.Devices() {
+			if e := d.inst.AttachDevice(dev); e != nil {
+				fmt.Println("[debug] Could not attach a device:", e.Error())
+			}
+		}
+	}
+	d.depth = 0
+	d.depths = nil
+	for addr := range d.watches {
+		d.watches[addr] = d.readWatch(addr)
+	}
+}
+
+func (d *debugger) readWatch(addr uint32) debugWatch {
+	v, ok := d.inst.MemPeek(addr)
+	return debugWatch{value: v, initialized: ok}
+}
+
+func (d *debugger) saveRegState() debugRegState {
+	var ret debugRegState
+	ret.regs, ret.regInit = d.inst.Registers()
+	ret.hi, ret.lo, ret.hiLoFilled = d.inst.HiLo()
+	ret.fpRegs, ret.fpRegInit = d.inst.FPRegisters()
+	return ret
+}
+
+//executes one instruction while keeping track of the call depth, returns false if the emulation has ended
+func (d *debugger) stepOne() bool {
+	if d.inst.Halted() {
+		return false
+	}
+
+	pc := d.inst.PC()
+	d.depths = append(d.depths, d.depth)
+	instr, _ := d.inst.MemPeek(pc)
+	op, x, _, z, _, fn := emu.DecodeInstruction(instr)
+	if instr != 0 && op == 0x0 && fn == emu.FnJR && x == 31 {
+		d.depth--
+	}
+
+	d.inst.Step()
+
+	//a call is a linking instruction that did not fall through, which bltzal and bgezal do when not taken
+	link := op == emu.OpJAL || instr != 0 && op == 0x0 && fn == emu.FnJALR ||
+		op == emu.OpREGIMM && (z == emu.RtBLTZAL || z == emu.RtBGEZAL)
+	if link && d.inst.PC() != pc+4 {
+		d.depth++
+	}
+	return !d.inst.Halted()
+}
+
+//undoes the last instruction executed while keeping track of the call depth, returns false at the start of the program
+func (d *debugger) stepBack() bool {
+	n := d.inst.UndoLen()
+	if n == 0 {
+		return false
+	}
+
+	if d.inst.Undo() {
+		d.depth = d.depths[n-1]
+		d.depths = d.depths[:n-1]
+		return true
+	}
+
+	//the instruction reached a software interrupt or a custom device, whose state cannot be undone. The emulation is
+	//deterministic, so emulating again from the start up to the instruction before gives the same state
+	watches := make(map[uint32]debugWatch)
+	for addr, w := range d.watches {
+		watches[addr] = w
+	}
+	d.restart(d.inst.Seed())
+	for d.inst.UndoLen() < n-1 && !d.inst.Halted() {
+		d.stepOne()
+	}
+	d.watches = watches
+	return true
+}
+
+//returns true if a breakpoint is reached or a watched word changed, or the stop condition is met
+func (d *debugger) shouldStop(stop func() bool) bool {
+	for addr, w := range d.watches {
+		nw := d.readWatch(addr)
+		if nw != w {
+			d.watches[addr] = nw
+			fmt.Printf("[debug] Watched word *0x%X changed from %s to %s\n", addr, formatWatch(w), formatWatch(nw))
+			return true
+		}
+	}
+
+	if d.breakpoints[d.inst.PC()] {
+		fmt.Printf("[debug] Breakpoint at 0x%X reached\n", d.inst.PC())
+		return true
+	}
+
+	return stop != nil && stop()
+}
+
+//steps until the stop condition is met, a breakpoint is reached, a watched word changes or the emulation ends
+//the stop condition is checked after every instruction; a nil condition runs until a breakpoint
+func (d *debugger) run(stop func() bool) {
+	for d.stepOne() {
+		if d.shouldStop(stop) {
+			return
+		}
+	}
+}
+
+//steps backwards like run, until the start of the program at the latest
+func (d *debugger) runBack(stop func() bool) {
+	for {
+		if !d.stepBack() {
+			fmt.Println("[debug] Reached the start of the program")
+			return
+		}
+
+		if d.shouldStop(stop) {
+			return
+		}
+	}
+}
+
+//displays the instruction that last wrote the register or word of memory
+func (d *debugger) lastWrite(target string) {
+	var w emu.UndoWrite
+	var ok bool
+	var desc string
+	if strings.HasPrefix(target, "*") {
+		addr, e := asm.LiteralValue(strings.Trim(target, "*"), d.labels)
+		if e != nil {
+			fmt.Println("[debug] Invalid memory address:", e.Error())
+			return
+		}
+		addr &= 0xFFFFFFFC
+		w, ok = d.inst.LastMemWrite(addr)
+		desc = fmt.Sprintf("*0x%X", addr)
+	} else if t := strings.Trim(target, "$"); len(t) > 1 && (t[0] == 'f' || t[0] == 'F') && unicode.IsDigit(rune(t[1])) {
+		reg, e := strconv.Atoi(t[1:])
+		if e != nil || reg < 0 || reg > 31 {
+			fmt.Println("[debug] Invalid floating-point register, expected $f0 to $f31.")
+			return
+		}
+		w, ok = d.inst.LastRegWrite(reg, true)
+		desc = emu.FormatFPRegister(reg)
+	} else {
+		reg, e := parseRegister(target)
+		if e != nil {
+			fmt.Println("[debug] Invalid register:", e.Error())
+			return
+		}
+		w, ok = d.inst.LastRegWrite(reg, false)
+		desc = emu.FormatRegister(reg)
+	}
+
+	if !ok {
+		fmt.Printf("[debug] %s has not been written since the start of the program\n", desc)
+		return
+	}
+
+	where := "no corresponding line of assembly"
+	if l, found := d.lineMeta[w.PC]; found {
+		where = fmt.Sprintf("line %d: %s", l.LineNumber, strings.Trim(l.Contents, " \t"))
+	}
+	fmt.Printf("[debug] %s was last written at pc=0x%X di=%d (%s), from %d (0x%X) to %d (0x%X)\n", desc, w.PC, w.DI,
+		where, w.Old, w.Old, w.New, w.New)
+}
+
+func formatWatch(w debugWatch) string {
+	if !w.initialized {
+		return "uninitialized"
+	}
+
+	return fmt.Sprintf("%d (0x%X)", w.value, w.value)
+}
+
+//displays where the debugger has stopped and which registers changed since the given state
+func (d *debugger) displayStop(prev debugRegState) {
+	cur := d.saveRegState()
+	for i := 1; 32 > i; i++ {
+		wasInit := (prev.regInit>>i)&0x1 == 0x1
+		isInit := (cur.regInit>>i)&0x1 == 0x1
+		if isInit && (!wasInit || prev.regs[i] != cur.regs[i]) {
+			fmt.Printf("[debug] %s = %d (0x%X)\n", emu.FormatRegister(i), cur.regs[i], cur.regs[i])
+		}
+	}
+	for i := 0; 32 > i; i++ {
+		wasInit := (prev.fpRegInit>>i)&0x1 == 0x1
+		isInit := (cur.fpRegInit>>i)&0x1 == 0x1
+		if isInit && (!wasInit || prev.fpRegs[i] != cur.fpRegs[i]) {
+			fmt.Printf("[debug] %s = %g (0x%X)\n", emu.FormatFPRegister(i), math.Float32frombits(cur.fpRegs[i]),
+				cur.fpRegs[i])
+		}
+	}
+	if cur.hiLoFilled && (!prev.hiLoFilled || prev.hi != cur.hi) {
+		fmt.Printf("[debug] hi = %d (0x%X)\n", cur.hi, cur.hi)
+	}
+	if cur.hiLoFilled && (!prev.hiLoFilled || prev.lo != cur.lo) {
+		fmt.Printf("[debug] lo = %d (0x%X)\n", cur.lo, cur.lo)
+	}
+
+	d.displayLocation()
+}
+
+func (d *debugger) displayLocation() {
+	if d.inst.Halted() {
+		fmt.Printf("[debug] The emulation has ended after %d instructions with %d error(s). Use 'restart' to debug again.\n",
+			d.inst.DI(), len(d.inst.Errors()))
+		return
+	}
+
+	l, ok := d.lineMeta[d.inst.PC()]
+	if !ok {
+		fmt.Printf("[debug] pc=0x%X di=%d (no corresponding line of assembly)\n", d.inst.PC(), d.inst.DI())
+		return
+	}
+
+	fmt.Printf("[debug] pc=0x%X di=%d line %d: %s\n", d.inst.PC(), d.inst.DI(), l.LineNumber, l.Contents)
+}
+
+//resolves a breakpoint target, which can be a label, a decimal line number, or a hexadecimal address
+func (d *debugger) resolveTarget(target string) (uint32, error) {
+	if strings.HasPrefix(strings.ToLower(target), "0x") {
+		return asm.LiteralValue(target, nil)
+	}
+
+	if n, e := strconv.Atoi(target); e == nil {
+		//line number, using the lowest address assembled from that line
+		found := false
+		var ret uint32
+		for addr, l := range d.lineMeta {
+			if l.LineNumber == n && (!found || addr < ret) {
+				ret = addr
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("line %d does not contain an instruction", n)
+		}
+		return ret, nil
+	}
+
+	v, ok := d.labels[target]
+	if !ok {
+		return 0, fmt.Errorf("unresolved label \"%s\"", target)
+	}
+	return v, nil
+}
+
+//the debugger's command loop, returns when the user leaves the debugger
+func (d *debugger) start(reader *bufio.Reader) {
+	fmt.Println("[debug] Debugging the selected snapshot from the start of the program. Type 'help' for debugger commands.")
+	d.displayLocation()
+
+	for {
+		fmt.Print("D> ")
+		input, e := reader.ReadString('\n')
+		if e != nil {
+			return
+		}
+
+		input = strings.Trim(input, "\n \t\r")
+		fields := strings.Fields(input)
+		if len(fields) == 0 {
+			continue
+		}
+
+		prev := d.saveRegState()
+
+		switch strings.ToLower(fields[0]) {
+		case "help":
+			displayDebugHelp()
+		case "quit", "exit":
+			fmt.Println("[debug] Leaving the debugger.")
+			return
+		case "step", "s":
+			n := 1
+			if len(fields) == 2 {
+				v, e := strconv.Atoi(fields[1])
+				if e != nil || v <= 0 {
+					fmt.Println("[debug] Invalid step count, expected a positive integer.")
+					continue
+				}
+				n = v
+			}
+			count := 0
+			d.run(func() bool {
+				count++
+				return count >= n
+			})
+			d.displayStop(prev)
+		case "next", "n":
+			depth := d.depth
+			d.run(func() bool {
+				return d.depth <= depth
+			})
+			d.displayStop(prev)
+		case "finish":
+			depth := d.depth
+			d.run(func() bool {
+				return d.depth < depth
+			})
+			d.displayStop(prev)
+		case "continue", "c":
+			d.run(nil)
+			d.displayStop(prev)
+		case "reverse-step", "rs":
+			n := 1
+			if len(fields) == 2 {
+				v, e := strconv.Atoi(fields[1])
+				if e != nil || v <= 0 {
+					fmt.Println("[debug] Invalid step count, expected a positive integer.")
+					continue
+				}
+				n = v
+			}
+			count := 0
+			d.runBack(func() bool {
+				count++
+				return count >= n
+			})
+			d.displayStop(prev)
+		case "reverse-next", "rn":
+			depth := d.depth
+			d.runBack(func() bool {
+				return d.depth <= depth
+			})
+			d.displayStop(prev)
+		case "reverse-finish":
+			depth := d.depth
+			d.runBack(func() bool {
+				return d.depth < depth
+			})
+			d.displayStop(prev)
+		case "reverse-continue", "rc":
+			d.runBack(nil)
+			d.displayStop(prev)
+		case "lastwrite":
+			if len(fields) != 2 {
+				fmt.Println("[debug] Invalid format, expected 'lastwrite $[register]' or 'lastwrite *[address]'.")
+				continue
+			}
+			d.lastWrite(fields[1])
+		case "break", "b":
+			if len(fields) == 1 {
+				fmt.Printf("[debug] %d breakpoint(s) set.\n", len(d.breakpoints))
+				for addr := range d.breakpoints {
+					fmt.Printf("[debug] Breakpoint at 0x%X\n", addr)
+				}
+				continue
+			}
+			addr, e := d.resolveTarget(fields[1])
+			if e != nil {
+				fmt.Println("[debug] Invalid breakpoint:", e.Error())
+				continue
+			}
+			d.breakpoints[addr] = true
+			fmt.Printf("[debug] Breakpoint set at 0x%X\n", addr)
+		case "delete":
+			if len(fields) != 2 {
+				fmt.Println("[debug] Invalid format, expected 'delete [label|line|address]'.")
+				continue
+			}
+			addr, e := d.resolveTarget(fields[1])
+			if e != nil || !d.breakpoints[addr] {
+				fmt.Println("[debug] There is no breakpoint there.")
+				continue
+			}
+			delete(d.breakpoints, addr)
+			fmt.Printf("[debug] Breakpoint at 0x%X deleted\n", addr)
+		case "watch", "unwatch":
+			if len(fields) != 2 || fields[1][0] != '*' {
+				fmt.Printf("[debug] Invalid format, expected '%s *[address]'.\n", strings.ToLower(fields[0]))
+				continue
+			}
+			addr, e := asm.LiteralValue(strings.Trim(fields[1], "*"), d.labels)
+			if e != nil {
+				fmt.Println("[debug] Invalid memory address:", e.Error())
+				continue
+			}
+			addr &= 0xFFFFFFFC
+			if strings.ToLower(fields[0]) == "watch" {
+				d.watches[addr] = d.readWatch(addr)
+				fmt.Printf("[debug] Watching *0x%X, currently %s\n", addr, formatWatch(d.watches[addr]))
+			} else {
+				delete(d.watches, addr)
+				fmt.Printf("[debug] No longer watching *0x%X\n", addr)
+			}
+		case "where":
+			d.displayLocation()
+		case "restart":
+			d.restart(d.inst.Seed())
+			d.displayLocation()
+		case "errors":
+			res := d.inst.Result()
+			errorsCommand(&res)
+		case "cp0":
+			res := d.inst.Result()
+			displayCP0(&res)
+		case "output":
+			res := d.inst.Result()
+			displayOutput(&res)
+		case "savebitmap":
+			res := d.inst.Result()
+			saveBitmapCommand(&res, fields)
+		default:
+			res := d.inst.Result()
+			if fields[0][0] == '$' {
+				displayRegisters(&res, input)
+			} else if fields[0][0] == '*' {
+				displayMemory(&res, input, d.labels)
+			} else {
+				fmt.Printf("[debug] Unknown command \"%s\". Type 'help' for debugger commands.\n", fields[0])
+			}
+		}
+	}
+}
+
+func displayDebugHelp() {
+	fmt.Println("+==== DEBUGGER HELP ====+")
+	fmt.Println("step [count] | executes the next instruction, or the next count instructions. Short form: 's'")
+	fmt.Println("next | executes the next instruction, stepping over function calls. Short form: 'n'")
+	fmt.Println("finish | runs until the current function returns with jr $31")
+	fmt.Println("continue | runs until a breakpoint, a watched word changes, or the program ends. Short form: 'c'")
+	fmt.Println("reverse-step [count] | undoes the last instruction, or the last count instructions. Short form: 'rs'")
+	fmt.Println("reverse-next | undoes the last instruction, stepping back over function calls. Short form: 'rn'")
+	fmt.Println("reverse-finish | runs backwards to the call of the current function")
+	fmt.Println("reverse-continue | runs backwards until a breakpoint, a watched word changes, or the start. Short form: 'rc'")
+	fmt.Println("lastwrite $[register] | *[address] | displays the instruction that last wrote the register or word")
+	fmt.Println(" - Example usage: 'lastwrite $v0', 'lastwrite $f2', 'lastwrite *0x4000'")
+	fmt.Println("break [label|line|address] | sets a breakpoint. Lines are in decimal, addresses in hexadecimal")
+	fmt.Println(" - With no target, lists all breakpoints. Example usage: 'break loopStart', 'break 12', 'break 0x40'")
+	fmt.Println("delete [label|line|address] | removes a breakpoint")
+	fmt.Println("watch *[address] | stops whenever the word at the address changes. Example usage: 'watch *0x4000'")
+	fmt.Println("unwatch *[address] | stops watching the word at the address")
+	fmt.Println("where | displays the current pc and line of assembly")
+	fmt.Println("restart | starts debugging from the beginning of the program again")
+	fmt.Println("errors | displays the runtime errors so far")
+	fmt.Println("cp0 | displays the coprocessor 0 registers, for programs with an exception handler")
+	fmt.Println("output | displays the console output of the syscall services and the MMIO display so far")
+	fmt.Println("savebitmap [file] | saves the bitmap display so far, as in the explorer")
+	fmt.Println("$[register], *[address] | displays current register and memory contents, as in the explorer")
+	fmt.Println("quit | leaves the debugger and returns to the explorer")
+}
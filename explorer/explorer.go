@@ -0,0 +1,699 @@
+//Package explorer is the interactive command-line interface for inspecting the results of a vet session
+package explorer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/vet"
+)
+
+/**
+ * The explorer is the command-line interface for interacting with the results after emulation has completed.
+ * The explorer views the results of the emulation including the final state of snap shots, and the 'debug' command
+ * starts a live debugger (see debugger.go) on the selected snapshot's scenario.
+ *
+ * Having said that, the vetting system captures select snapshots of failed tests, which may be useful.
+ * The via the explorer, the following information is available about a given snapshot:
+ *  - All register final values
+ *  - All memory values
+ *  - Label evaluations
+ *  - Vet scenario
+ *  - Specific runtime errors
+ */
+
+//Start runs the explorer on the standard input until the user exits. vSession may be nil if nothing was vetted
+func Start(latest emu.EmulationResult, vSession *vet.Session, labels map[string]uint32, lineMeta map[uint32]asm.InputLine,
+	program *Program) {
+	fmt.Println("\n+==== [ EXPLORER ]====+")
+	fmt.Println("The explorer lets you explore failed cases or the last emulation.")
+	fmt.Println("The current selection is the latest emulation, and does not necessarily mean it is a failed case.")
+	numSnap := 1
+	if vSession != nil {
+		numSnap += len(vSession.FailedSnapshots)
+	}
+	fmt.Printf("Captured %d snapshots.\n", numSnap)
+	fmt.Println("Type 'quit' to exit. Type 'help' for command assistance.")
+
+	reader := bufio.NewReader(os.Stdin)
+	selectionIndex := 0
+	selection := &latest
+	for true {
+		fmt.Printf("R%d> ", selectionIndex)
+		input, e := reader.ReadString('\n')
+		if e != nil {
+			fmt.Println("\nExiting...")
+			return
+		}
+
+		input = strings.Trim(input, "\n \t\r")
+		iLower := strings.ToLower(input)
+
+		if iLower == "help" {
+			displayHelp()
+			continue
+		} else if iLower == "quit" {
+			fmt.Println("\nExiting...")
+			return
+		}
+
+		fields := strings.Fields(iLower)
+		oFields := strings.Fields(input)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "search" {
+			//search command
+			if vSession != nil {
+				searchCommand(vSession.FailedSnapshots, fields)
+			} else {
+				searchCommand(nil, fields)
+			}
+		} else if fields[0] == "cr" {
+			//change result command
+			nSel := changeResultCommand(numSnap, oFields)
+			if nSel == 0 {
+				selectionIndex = 0
+				selection = &latest
+			} else if nSel != -1 && vSession != nil {
+				selectionIndex = nSel
+				selection = &vSession.FailedSnapshots[nSel-1].Snapshot
+			}
+		} else if fields[0] == "label" {
+			//label decode command
+			if len(oFields) != 2 {
+				fmt.Println("[label] Invalid format, expected 'label myLabel'.")
+				continue
+			}
+
+			res, e := asm.LiteralValue(oFields[1], labels)
+			if e != nil {
+				fmt.Println("[label] Invalid label:", e.Error())
+				continue
+			}
+			fmt.Printf("[label] %s evaluates to %d (0x%X)\n", oFields[1], res, res)
+		} else if fields[0] == "decode" {
+			//address decode command
+			if len(oFields) != 2 {
+				fmt.Println("[decode] Invalid format, expected 'decode 0x1000'.")
+				continue
+			}
+
+			res, e := asm.LiteralValue(oFields[1], labels)
+			if e != nil {
+				fmt.Println("[decode] Invalid address:", e.Error())
+				continue
+			}
+			l, ok := lineMeta[res]
+			if !ok {
+				fmt.Printf("[decode] %s does not correspond to a line of assembly.\n", oFields[1])
+			} else {
+				fmt.Printf("[decode] %s corresponds to line %d \"%s\"\n", oFields[1], l.LineNumber, l.Contents)
+			}
+		} else if fields[0] == "scenario" {
+			//scenario command
+			displayScenario(selection)
+		} else if fields[0] == "errors" {
+			//errors display command
+			errorsCommand(selection)
+		} else if fields[0] == "cp0" {
+			//exception state display
+			displayCP0(selection)
+		} else if fields[0] == "output" {
+			//console output display
+			displayOutput(selection)
+		} else if fields[0] == "branches" {
+			//branch analysis of the snapshot
+			branches := make(vet.BranchAnalysis)
+			branches.Add(selection.BranchAnalysis)
+			vet.DisplayBranchAnalysis(branches, program.Memory, lineMeta)
+			fmt.Println()
+		} else if fields[0] == "profile" {
+			profileCommand(program, lineMeta, oFields)
+		} else if fields[0] == "calls" {
+			callsCommand(program, labels, oFields)
+		} else if fields[0] == "coverage" {
+			coverageCommand(program, oFields)
+		} else if fields[0] == "trace" {
+			traceCommand(selection, lineMeta, oFields)
+		} else if fields[0] == "debug" {
+			//live debugging of the selected snapshot
+			newDebugger(program, selection.Seed, labels, lineMeta).start(reader)
+		} else if fields[0] == "saveimage" {
+			saveImageCommand(selection, vSession)
+		} else if fields[0] == "savebitmap" {
+			saveBitmapCommand(selection, oFields)
+		} else if fields[0] == "dump" {
+			saveDumpCommand(selection, vSession)
+		} else if len(fields[0]) > 0 && fields[0][0] == '$' {
+			//register display
+			displayRegisters(selection, input)
+		} else if len(fields[0]) > 0 && fields[0][0] == '*' {
+			//memory display
+			displayMemory(selection, input, labels)
+		}
+	}
+}
+
+func displayHelp() {
+	fmt.Println("+==== HELP ====+")
+	fmt.Println("search [testcase] [optional: testcase 2]... | Returns list of failed result indices for the given test cases.")
+	fmt.Println(" - These test cases should be from different categories.")
+	fmt.Println(" - Example usage: 'search 1hLines ObsNone GeoL'")
+	fmt.Println("cr [result index] | Changes current result to the specified index")
+	fmt.Println(" - Note that index 0 is the last emulation result and is always available.")
+	fmt.Println(" - Example usage: 'cr 2'")
+	fmt.Println("$[register] | displays last register contents for the given register.")
+	fmt.Println(" - Can be used in a range, for example: '$3 - 6' prints all register values in that range.")
+	fmt.Println(" - Registers can also be named, for example: '$sp' or '$t0 - t7'.")
+	fmt.Println(" - Floating-point registers are shown as floats, and even ones also as doubles, for example: '$f0 - f3'.")
+	fmt.Println(" - Example usage: '$3'")
+	fmt.Println("*[address] | displays last contents of that memory address")
+	fmt.Println(" - Can be used in a range to print all contents within the range, example: '*0x400 - 0x40F'")
+	fmt.Println(" - Addresses can be specified in hex, decimal, or label")
+	fmt.Println(" - Example usage: '*5475'")
+	fmt.Println("label [label name] | displays the value the label evaluates to.")
+	fmt.Println(" - Example usage: 'label loopStart'")
+	fmt.Println("decode [address] | displays the line of assembly that corresponds to that address")
+	fmt.Println(" - Addresses can be specified in hex, decimal, or label")
+	fmt.Println(" - Example usage: 'decode 0x4004'")
+	fmt.Println("errors | displays all errors for the current result snapshot")
+	fmt.Println(" - Example usage: 'errors'")
+	fmt.Println("cp0 | displays the coprocessor 0 registers (Status, Cause, EPC and BadVAddr) of programs with a .ktext handler")
+	fmt.Println(" - Example usage: 'cp0'")
+	fmt.Println("output | displays the console output of the syscall services and the MMIO display for the current result snapshot")
+	fmt.Println(" - Example usage: 'output'")
+	fmt.Println("branches | displays how often every conditional branch was taken in the current result snapshot")
+	fmt.Println(" - Example usage: 'branches'")
+	fmt.Println("profile [file] | displays the source annotated with how often every line was executed over all samples")
+	fmt.Println(" - Requires -profile. With a file, saves the listing instead, as HTML if it ends in .html")
+	fmt.Println(" - Example usage: 'profile', 'profile profile.html'")
+	fmt.Println("calls [file] | displays the instructions executed in every function and the call graph over all samples")
+	fmt.Println(" - Requires -calls. With a file, saves the collapsed stacks for flame graph tools instead")
+	fmt.Println(" - Example usage: 'calls', 'calls stacks.txt'")
+	fmt.Println("coverage [file] | displays the lines and branch directions no sample exercised")
+	fmt.Println(" - Requires -coverage. With a file, saves the source highlighted by coverage as HTML instead")
+	fmt.Println(" - Example usage: 'coverage', 'coverage coverage.html'")
+	fmt.Println("trace [count or file] | displays the last instructions executed by the current result snapshot, 20 by default")
+	fmt.Println(" - Requires -trace. With a file, saves the whole trace instead, as JSON lines if it ends in .jsonl")
+	fmt.Println(" - Example usage: 'trace', 'trace 100', 'trace trace.jsonl'")
+	fmt.Println("scenario | displays scenario information and the sample index of the current snapshot")
+	fmt.Println(" - Example usage: 'scenario'")
+	fmt.Println()
+	fmt.Println("debug | starts the live debugger on the current snapshot's scenario, from the start of the program")
+	fmt.Println(" - Type 'help' within the debugger for its commands")
+	fmt.Println("saveimage | saves the image of the current snapshot's test case")
+	fmt.Println(" - Example usage: 'saveimage'")
+	fmt.Println("savebitmap [file] | saves the bitmap display of the current snapshot, as an animation if the file ends in .gif")
+	fmt.Println(" - Defaults to bitmap.png. Example usage: 'savebitmap frames.gif'")
+	fmt.Println("dump | generates a dump file of the test case of the current snapshot that can be imported to MiSaSiM")
+	fmt.Println(" - Example usage: 'dump'")
+}
+
+//the assignment of the vet session, or when not vetting, every registered assignment
+func candidateAssignments(vSession *vet.Session) []vet.Assignment {
+	if vSession != nil {
+		return []vet.Assignment{vSession.Definition()}
+	}
+
+	return vet.List()
+}
+
+func saveImageCommand(snap *emu.EmulationResult, vSession *vet.Session) {
+	var lastErr error
+	for _, a := range candidateAssignments(vSession) {
+		if exporter, ok := a.(vet.ImageExporter); ok {
+			lastErr = exporter.SaveImage(snap)
+			if lastErr == nil {
+				return
+			}
+		}
+	}
+
+	if lastErr == nil {
+		fmt.Println("[saveimage] The assignment does not support saving images.")
+		return
+	}
+	fmt.Println("[saveimage] Failed to save the image:", lastErr.Error())
+}
+
+func saveBitmapCommand(snap *emu.EmulationResult, fields []string) {
+	fileName := "bitmap.png"
+	if len(fields) == 2 {
+		fileName = fields[1]
+	} else if len(fields) > 2 {
+		fmt.Println("[savebitmap] Invalid format, expected 'savebitmap [file]'.")
+		return
+	}
+
+	for _, d := range snap.Devices {
+		fb, ok := d.(*emu.Framebuffer)
+		if !ok {
+			continue
+		}
+
+		var e error
+		if strings.HasSuffix(strings.ToLower(fileName), ".gif") {
+			e = fb.SaveGIF(fileName, snap.DI)
+		} else {
+			e = fb.SavePNG(fileName)
+		}
+		if e != nil {
+			fmt.Println("[savebitmap] Failed to save the bitmap display:", e.Error())
+			return
+		}
+		fmt.Println("[savebitmap] Saved the bitmap display to " + fileName)
+		return
+	}
+
+	fmt.Println("[savebitmap] The snapshot has no bitmap display, attach one with -bitmap.")
+}
+
+func profileCommand(program *Program, lineMeta map[uint32]asm.InputLine, fields []string) {
+	if program.Profile == nil {
+		fmt.Println("[profile] The emulation was not profiled, run it again with -profile.")
+		return
+	}
+
+	if len(fields) == 1 {
+		if e := program.Profile.WriteText(os.Stdout, program.Source, lineMeta); e != nil {
+			fmt.Println("[profile] Failed to display the profile:", e.Error())
+		}
+		fmt.Println()
+		return
+	} else if len(fields) != 2 {
+		fmt.Println("[profile] Invalid format, expected 'profile [file]'.")
+		return
+	}
+
+	if e := program.Profile.Save(fields[1], program.FileName, program.Source, lineMeta); e != nil {
+		fmt.Println("[profile]", e.Error())
+		return
+	}
+	fmt.Println("[profile] Saved the profile to " + fields[1])
+}
+
+func callsCommand(program *Program, labels map[string]uint32, fields []string) {
+	if program.Calls == nil {
+		fmt.Println("[calls] The calls were not profiled, run it again with -calls.")
+		return
+	}
+
+	names := vet.FunctionNames(labels)
+	if len(fields) == 1 {
+		if e := program.Calls.WriteReport(os.Stdout, names); e != nil {
+			fmt.Println("[calls] Failed to display the call profile:", e.Error())
+		}
+		fmt.Println()
+		return
+	} else if len(fields) != 2 {
+		fmt.Println("[calls] Invalid format, expected 'calls [file]'.")
+		return
+	}
+
+	if e := program.Calls.SaveCollapsed(fields[1], names); e != nil {
+		fmt.Println("[calls]", e.Error())
+		return
+	}
+	fmt.Println("[calls] Saved the collapsed stacks to " + fields[1])
+}
+
+func coverageCommand(program *Program, fields []string) {
+	if program.Coverage == nil {
+		fmt.Println("[coverage] The coverage was not measured, run it again with -coverage.")
+		return
+	}
+
+	if len(fields) == 1 {
+		vet.DisplayCoverage(program.Coverage)
+		fmt.Println()
+		return
+	} else if len(fields) != 2 {
+		fmt.Println("[coverage] Invalid format, expected 'coverage [file]'.")
+		return
+	}
+
+	if e := program.Coverage.Save(fields[1], program.FileName); e != nil {
+		fmt.Println("[coverage]", e.Error())
+		return
+	}
+	fmt.Println("[coverage] Saved the coverage report to " + fields[1])
+}
+
+func traceCommand(snap *emu.EmulationResult, lineMeta map[uint32]asm.InputLine, fields []string) {
+	if snap.Trace == nil {
+		fmt.Println("[trace] The emulation was not traced, run it again with -trace [n].")
+		return
+	} else if len(fields) > 2 {
+		fmt.Println("[trace] Invalid format, expected 'trace [count or file]'.")
+		return
+	}
+
+	count := 20
+	if len(fields) == 2 {
+		n, e := strconv.Atoi(fields[1])
+		if e != nil {
+			//not a count, so a file
+			if e := vet.SaveTrace(fields[1], snap.Trace, lineMeta); e != nil {
+				fmt.Println("[trace]", e.Error())
+				return
+			}
+			fmt.Println("[trace] Saved the trace to " + fields[1])
+			return
+		} else if n <= 0 {
+			fmt.Println("[trace] The count must be greater than 0.")
+			return
+		}
+		count = n
+	}
+
+	entries := snap.Trace
+	if len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+	fmt.Printf("[trace] The last %d instruction(s), from the oldest:\n", len(entries))
+	if e := vet.WriteTrace(os.Stdout, entries, lineMeta, false); e != nil {
+		fmt.Println("[trace] Failed to display the trace:", e.Error())
+	}
+}
+
+func saveDumpCommand(snap *emu.EmulationResult, vSession *vet.Session) {
+	var lastErr error
+	for _, a := range candidateAssignments(vSession) {
+		if exporter, ok := a.(vet.DumpExporter); ok {
+			lastErr = exporter.SaveDump(snap)
+			if lastErr == nil {
+				return
+			}
+		}
+	}
+
+	if lastErr == nil {
+		fmt.Println("[dump] The assignment does not support dump files.")
+		return
+	}
+	fmt.Println("[dump] Failed to save the dump file:", lastErr.Error())
+}
+
+func displayCP0(snap *emu.EmulationResult) {
+	if snap.CP0[emu.CP0Status] == 0 {
+		fmt.Println("[cp0] The exception model was not enabled, the program has no exception handler (.ktext).")
+		fmt.Println()
+		return
+	}
+
+	cause := snap.CP0[emu.CP0Cause]
+	excCode := int(cause>>2) & 0x1F
+	fmt.Printf("[cp0] Status = 0x%X (exception level %d)\n", snap.CP0[emu.CP0Status], (snap.CP0[emu.CP0Status]>>1)&0x1)
+	fmt.Printf("[cp0] Cause = 0x%X (exception code %d: %s)\n", cause, excCode, emu.DecodeExceptionCode(excCode))
+	fmt.Printf("[cp0] EPC = 0x%X\n", snap.CP0[emu.CP0EPC])
+	fmt.Printf("[cp0] BadVAddr = 0x%X\n", snap.CP0[emu.CP0BadVAddr])
+	fmt.Println()
+}
+
+func displayOutput(snap *emu.EmulationResult) {
+	display := ""
+	for _, d := range snap.Devices {
+		if disp, ok := d.(*emu.Display); ok {
+			display = disp.Output()
+		}
+	}
+	if snap.Output == "" && display == "" {
+		fmt.Println("[output] This snapshot has no console or display output.")
+		fmt.Println()
+		return
+	}
+
+	if snap.Output != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(snap.Output, "\n"), "\n") {
+			fmt.Printf("[output] %s\n", line)
+		}
+	}
+	if display != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(display, "\n"), "\n") {
+			fmt.Printf("[display] %s\n", line)
+		}
+	}
+	if snap.ExitCode != 0 {
+		fmt.Printf("[output] The program exited with code %d.\n", snap.ExitCode)
+	}
+	fmt.Println()
+}
+
+func errorsCommand(snap *emu.EmulationResult) {
+	if len(snap.Errors) == 0 {
+		fmt.Println("[errors] This snapshot has no errors.")
+		fmt.Println()
+		return
+	}
+
+	for _, e := range snap.Errors {
+		fmt.Printf("[errors] %s; %s\n", emu.DecodeErrorCode(e.EType), e.Message)
+	}
+
+	fmt.Println()
+}
+
+func changeResultCommand(numSnap int, fields []string) int {
+	if len(fields) != 2 {
+		fmt.Println("[cr] invalid command usage, expected an index of the target snapshot. Use the search command to find indices.")
+		return -1
+	}
+
+	n, e := strconv.Atoi(fields[1])
+	if e != nil {
+		fmt.Println("[cr] invalid index: the index is not an integer in base 10.")
+		return -1
+	}
+
+	if n >= numSnap || n < 0 {
+		fmt.Println("[cr] invalid index: the index does not exist.")
+		return -1
+	}
+
+	return n
+}
+
+func searchCommand(snaps []vet.Snapshot, fields []string) {
+	results := make(map[int]string)
+
+	for i := 1; len(fields) > i; i++ {
+		for j := 0; len(snaps) > j; j++ {
+			if strings.Contains(strings.ToLower(snaps[j].TestCase), strings.ToLower("-"+fields[i])) {
+				//adding the result
+				results[j] = snaps[j].TestCase
+			}
+		}
+	}
+
+	fmt.Printf("[search] Found %d results.\n", len(results))
+
+	for i, s := range results {
+		fmt.Printf("[search] Index %d: %s\n", i+1, s)
+	}
+
+	fmt.Println()
+}
+
+func displayMemory(snap *emu.EmulationResult, input string, labels map[string]uint32) {
+	input = strings.Trim(input, "*")
+	if strings.Contains(input, "-") {
+		//range
+		r := strings.Split(input, "-")
+		if len(r) != 2 {
+			fmt.Println("[memory] Invalid range format. Expected '*0x100 - 0x110'")
+			return
+		}
+		a1 := strings.Trim(r[0], " *")
+		a2 := strings.Trim(r[1], " *")
+		a1v, e := asm.LiteralValue(a1, labels)
+		if e != nil {
+			fmt.Printf("[memory] Invalid memory address: %s\n", e.Error())
+			return
+		}
+		a2v, e := asm.LiteralValue(a2, labels)
+		if e != nil {
+			fmt.Printf("[memory] Invalid memory address: %s\n", e.Error())
+			return
+		}
+
+		if a2v < a1v {
+			fmt.Println("[memory] Invalid range. Must be 'smaller - larger'")
+			return
+		}
+
+		for i := a1v; a2v >= i; i += 4 {
+			if i-a1v > 100 {
+				fmt.Printf("[memory] and %d more...\n", a2v-i)
+				break
+			}
+
+			mv, ok := snap.Memory.Read(i)
+			if !ok {
+				fmt.Printf("[memory] *0x%X = uninitialized\n", i)
+				continue
+			}
+
+			fmt.Printf("[memory] *0x%X = %d (0x%X)\n", i, mv, mv)
+		}
+		fmt.Println()
+	} else {
+		//no range, just single address
+		a, e := asm.LiteralValue(input, labels)
+		if e != nil {
+			fmt.Printf("[memory] Invalid memory address: %s\n", e.Error())
+			return
+		}
+
+		mv, ok := snap.Memory.Read(a)
+		if !ok {
+			fmt.Printf("[memory] *0x%X = uninitialized\n\n", a)
+			return
+		}
+
+		fmt.Printf("[memory] *0x%X = %d (0x%X)\n\n", a, mv, mv)
+	}
+}
+
+//parses a register of the '$' command, which can be numbered (8) or named (t0), with or without the '$'
+func parseRegister(s string) (int, error) {
+	s = strings.Trim(s, " $")
+	if v, ok := emu.LookupRegister(s); ok {
+		return v, nil
+	}
+
+	v, e := asm.LiteralValue(s, nil)
+	if e != nil {
+		return 0, fmt.Errorf("\"%s\" is not a register number or name", s)
+	}
+	if v > 31 {
+		return 0, fmt.Errorf("registers are between 0 and 31")
+	}
+
+	return int(v), nil
+}
+
+func displayRegisters(snap *emu.EmulationResult, input string) {
+	input = strings.Trim(input, "$")
+	if len(input) > 1 && (input[0] == 'f' || input[0] == 'F') && unicode.IsDigit(rune(input[1])) {
+		displayFPRegisters(snap, input)
+		return
+	}
+	if strings.Contains(input, "-") {
+		//range
+		r := strings.Split(input, "-")
+		if len(r) != 2 {
+			fmt.Println("[registers] Invalid range format. Expected '$3 - 6' or '$t0 - t7'")
+			return
+		}
+		a1v, e := parseRegister(r[0])
+		if e != nil {
+			fmt.Printf("[registers] Invalid register: %s\n", e.Error())
+			return
+		}
+		a2v, e := parseRegister(r[1])
+		if e != nil {
+			fmt.Printf("[registers] Invalid register: %s\n", e.Error())
+			return
+		}
+
+		if a2v < a1v {
+			fmt.Println("[registers] Invalid range. Must be 'smaller - larger'")
+			return
+		}
+
+		for i := a1v; a2v >= i; i += 1 {
+			mv, ok := snap.RegRead(i)
+			if !ok {
+				fmt.Printf("[memory] %s = uninitialized\n", emu.FormatRegister(i))
+				continue
+			}
+
+			fmt.Printf("[memory] %s = %d (0x%X)\n", emu.FormatRegister(i), mv, mv)
+		}
+		fmt.Println()
+	} else {
+		//no range, just single register
+		a, e := parseRegister(input)
+		if e != nil {
+			fmt.Printf("[registers] Invalid register: %s\n", e.Error())
+			return
+		}
+
+		mv, ok := snap.RegRead(a)
+		if !ok {
+			fmt.Printf("[registers] %s = uninitialized\n\n", emu.FormatRegister(a))
+			return
+		}
+
+		fmt.Printf("[registers] %s = %d (0x%X)\n\n", emu.FormatRegister(a), mv, mv)
+	}
+}
+
+//parses a floating-point register, with or without the "$f"
+func parseFPRegister(s string) (int, error) {
+	s = strings.TrimLeft(strings.Trim(s, " $"), "fF")
+	v, e := strconv.Atoi(s)
+	if e != nil || v < 0 || v > 31 {
+		return 0, fmt.Errorf("\"%s\" is not a floating-point register, they are between $f0 and $f31", s)
+	}
+
+	return v, nil
+}
+
+func displayFPRegisters(snap *emu.EmulationResult, input string) {
+	r := strings.Split(input, "-")
+	if len(r) > 2 {
+		fmt.Println("[registers] Invalid range format. Expected '$f0 - f6'")
+		return
+	}
+	first, e := parseFPRegister(r[0])
+	if e != nil {
+		fmt.Printf("[registers] Invalid register: %s\n", e.Error())
+		return
+	}
+	last := first
+	if len(r) == 2 {
+		last, e = parseFPRegister(r[1])
+		if e != nil {
+			fmt.Printf("[registers] Invalid register: %s\n", e.Error())
+			return
+		}
+		if last < first {
+			fmt.Println("[registers] Invalid range. Must be 'smaller - larger'")
+			return
+		}
+	}
+
+	for i := first; last >= i; i++ {
+		v, ok := snap.FPRegRead(i)
+		if !ok {
+			fmt.Printf("[registers] %s = uninitialized\n", emu.FormatFPRegister(i))
+			continue
+		}
+
+		fmt.Printf("[registers] %s = %g (0x%X)", emu.FormatFPRegister(i), math.Float32frombits(v), v)
+		if hi, ok := snap.FPRegRead(i + 1); ok && i%2 == 0 {
+			//the pair can also be read as a double
+			fmt.Printf(", as a double with %s: %g", emu.FormatFPRegister(i+1), math.Float64frombits(uint64(hi)<<32|uint64(v)))
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
+
+func displayScenario(selection *emu.EmulationResult) {
+	fmt.Printf("[scenario] Sample %d of the batch, emulation seed %d\n", selection.Sample, selection.Seed)
+	scen, _ := json.Marshal(selection.SWIContext)
+	fmt.Println("[scenario]", string(scen))
+	fmt.Println()
+}
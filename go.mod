@@ -0,0 +1,3 @@
+module github.com/danielcbailey/MIPSEmulator
+
+go 1.16
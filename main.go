@@ -4,163 +4,387 @@ import (
 	"bufio"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/explorer"
+	_ "github.com/danielcbailey/MIPSEmulator/projects" //registers the assignments
+	"github.com/danielcbailey/MIPSEmulator/vet"
 )
 
 /**
  * The entry point for the executable.
  * Admittedly, this file is poorly written but it is because it is specific to the executable and doesn't serve
- * much use outside of this context. By contrast, the assembler and emulator are intended to be repurpose-able.
+ * much use outside of this context. By contrast, the assembler (asm), emulator (emu) and vetter (vet) packages
+ * are intended to be repurpose-able.
+ *
+ * When arguments are given, the command line interface in cli.go is used. Otherwise, the wizard is used.
  */
 
-var reader *bufio.Reader
+//exit status codes for the executable
+const (
+	exitOK               = 0
+	exitFailed           = 1 //vet failures, runtime errors or too many infinite loops
+	exitUsage            = 2
+	exitEula             = 3
+	exitAssembly         = 4
+	exitFileAccess       = 5
+	defaultETol          = 5
+	defaultLimit         = 100000
+	defaultVetCount      = 100000
+	defaultFrameInterval = 1000 //instructions between the frames of the bitmap display animation
+	defaultTraceSize     = 1000 //instructions kept in the trace when only -trace-out is given
+)
+
+var reader *bufio.Reader //only set when running the wizard, nil when running non-interactively
+
+type runConfig struct {
+	asmFile       string
+	eTol          int
+	assignment    string //blank for no vetting
+	numSamples    int
+	seed          int64
+	sample        int //when not -1, only this sample of the batch with the given seed is emulated
+	limit         int
+	workers       int  //0 for one worker per CPU
+	pseudo        bool //accept pseudo-instructions, course vetting is strict
+	isa           emu.ISA
+	syscalls      bool                     //perform the syscall services
+	input         string                   //read by the input syscalls
+	mmio          bool                     //attach the MARS keyboard and display
+	keys          string                   //typed on the keyboard
+	bitmap        *emu.FramebufferSettings //nil for no bitmap display
+	bitmapScale   int
+	bitmapPNG     string //saves the bitmap display of the last emulation
+	bitmapGIF     string
+	frameInterval int
+	branches      bool   //display the branch analysis
+	profile       bool   //count the executions of every instruction
+	profileOut    string //saves the profile, blank to display it when there is no explorer
+	calls         bool   //profile the functions with a shadow call stack
+	callsOut      string //saves the collapsed stacks of the call profile for flame graphs
+	coverage      bool   //display the code coverage, which is measured from the profile
+	coverageOut   string //saves the coverage report as HTML
+	trace         int    //the number of instructions kept in the trace of every emulation, 0 for none
+	traceOut      string //saves the trace of the last emulation
+	explorer      bool
+}
 
 func main() {
-	//wizard instead of arguments for now
+	if len(os.Args) > 1 {
+		os.Exit(runCommandLine(os.Args[1:]))
+	}
+
+	//wizard when no arguments are given
 	reader = bufio.NewReader(os.Stdin)
 	validateEula(reader)
 	fmt.Println("Assembly file:")
 	asmFile, _ := reader.ReadString('\n')
 	asmFile = strings.Trim(asmFile, " \n\t\r")
 
-	b, e := ioutil.ReadFile(asmFile)
-	if e != nil {
-		fmt.Println("ERROR: Failed to open assembly file: " + e.Error())
-		fmt.Println("Press enter to exit..")
-		_, _ = reader.ReadByte()
-		return
+	cfg := runConfig{
+		asmFile:    asmFile,
+		eTol:       defaultETol,
+		numSamples: 1,
+		seed:       time.Now().UnixNano(),
+		sample:     -1,
+		limit:      defaultLimit,
+		syscalls:   true,
+		mmio:       true,
+		explorer:   true,
 	}
 
 	fmt.Println("Number of errors to tolerate per sample (blank will default to 5)")
 	numETol, _ := reader.ReadString('\n')
 	numETol = strings.Trim(numETol, " \n\t\r")
-	eTol := 5
 	if len(numETol) > 0 {
-		eTol, e = strconv.Atoi(numETol)
+		var e error
+		cfg.eTol, e = strconv.Atoi(numETol)
 		if e != nil {
 			fmt.Println("Invalid number, defaulting to 5. Error:", e.Error())
-			eTol = 5
+			cfg.eTol = defaultETol
 		}
-		if eTol <= 0 {
+		if cfg.eTol <= 0 {
 			fmt.Println("Errors to tolerate must be greater than 0. Will halt after x number of errors is accumulated.")
 			exit()
 		}
 	}
 
 	fmt.Println("Type the assignment to vet the assembly for. Leave blank for no vetting.")
-	fmt.Println("Options are: 'P1' for Project 1")
+	fmt.Println("Options are: " + vet.Describe())
 	vetReq, _ := reader.ReadString('\n')
 	vetReq = strings.Trim(vetReq, " \n\t\r")
-	numSamples := 1
-	var vetSession *VetSession
 	if len(vetReq) > 0 {
-		numSamples = 100000
-		switch strings.ToLower(vetReq) {
-		case "p1":
-			vetSession = newVet("Project 1 Fall 2021")
-			break
-		default:
+		if vet.Find(vetReq) != nil {
+			cfg.assignment = vetReq
+			cfg.numSamples = defaultVetCount
+		} else {
 			fmt.Println("unknown assignment to vet, continuing with no vet in 3 seconds")
 			time.Sleep(3 * time.Second)
-			numSamples = 1
 		}
 	}
 
-	rand.Seed(time.Now().UnixNano())
+	status := runSession(cfg)
+	if status == exitAssembly || status == exitFileAccess {
+		pause()
+	}
+	os.Exit(status)
+}
+
+//assembles, emulates and vets the assembly as described by the config and returns the exit status
+func runSession(cfg runConfig) int {
+	b, e := ioutil.ReadFile(cfg.asmFile)
+	if e != nil {
+		fmt.Println("ERROR: Failed to open assembly file: " + e.Error())
+		return exitFileAccess
+	}
+
+	var vetSession *vet.Session
+	if len(cfg.assignment) > 0 {
+		a := vet.Find(cfg.assignment)
+		if a == nil {
+			fmt.Println("ERROR: Unknown assignment to vet: " + cfg.assignment)
+			return exitUsage
+		}
+		vetSession = vet.NewSession(a, cfg.seed)
+	}
 
-	settings := AssemblySettings{
-		TextStart: 0x0000,
-		DataStart: 0x4000,
+	settings := asm.AssemblySettings{
+		TextStart:  0x0000,
+		DataStart:  0x4000,
+		KTextStart: 0x80000180, //the exception vector of MARS and SPIM
+		FileName:   cfg.asmFile,
+		Pseudo:     cfg.pseudo,
+		ISA:        cfg.isa,
 	}
 
-	sysMem, lineMeta, numE, labels := Assemble(string(b), settings)
-	if numE != 0 {
+	sysMem, lineMeta, diagnostics, labels := asm.Assemble(string(b), settings)
+	for _, d := range diagnostics {
+		fmt.Println(d.String())
+	}
+	if numE := asm.CountErrors(diagnostics); numE != 0 {
 		fmt.Printf("%d error(s) generated from assembler, not attempting emulation.\n", numE)
-		fmt.Println("Press enter to exit..")
-		_, _ = reader.ReadByte()
-		return
+		return exitAssembly
 	}
 
-	limit := 100000
+	//the exception model is only enabled for programs with an exception handler
+	_, hasHandler := lineMeta[settings.KTextStart]
 
-	var lastResult EmulationResult
-	numInf := 0
-	dimin := limit
-	dimax := 0
-	avgDI := 0.0
-	var sysMemCopy SystemMemory
-	t := time.Now()
-	for i := 0; numSamples > i; i++ {
-		//creating a copy of the memory
-		sysMemCopy = make(SystemMemory)
-		for k, v := range sysMem {
-			newPage := MemoryPage{
-				startAddr:   v.startAddr,
-				memory:      make([]uint32, len(v.memory)),
-				initialized: make([]uint32, len(v.initialized)),
+	var devices func() []emu.Device
+	if cfg.mmio || cfg.bitmap != nil {
+		devices = func() []emu.Device {
+			var ret []emu.Device
+			if cfg.mmio {
+				ret = append(ret, emu.NewKeyboard(cfg.keys, emu.DefaultTerminalDelay),
+					emu.NewDisplay(emu.DefaultTerminalDelay))
 			}
+			if cfg.bitmap != nil {
+				fb, _ := emu.NewFramebuffer(*cfg.bitmap) //the settings were checked when parsed
+				ret = append(ret, fb)
+			}
+			return ret
+		}
+	}
 
-			copy(newPage.memory, v.memory)
-			copy(newPage.initialized, v.initialized)
+	batchSettings := vet.BatchSettings{
+		StartAddr:   settings.TextStart,
+		NumSamples:  cfg.numSamples,
+		Seed:        cfg.seed,
+		Limit:       uint32(cfg.limit),
+		ETol:        cfg.eTol,
+		ISA:         cfg.isa,
+		Exceptions:  hasHandler,
+		Handler:     settings.KTextStart,
+		Syscalls:    cfg.syscalls,
+		Input:       cfg.input,
+		Devices:     devices,
+		Profile:     cfg.profile || cfg.coverage,
+		CallProfile: cfg.calls,
+		Trace:       cfg.trace,
+		Workers:     cfg.workers,
+		Progress:    true,
+	}
+	if cfg.sample >= 0 {
+		batchSettings.NumSamples = 1
+		batchSettings.FirstSample = cfg.sample
+		fmt.Printf("Emulating sample %d of the batch with seed %d.\n", cfg.sample, cfg.seed)
+	} else {
+		fmt.Printf("Batch seed: %d (use -seed %d -sample [index] to emulate a single sample again)\n", cfg.seed, cfg.seed)
+	}
 
-			sysMemCopy[k] = newPage
-		}
+	t := time.Now()
+	batch, e := vet.RunBatch(sysMem, batchSettings, vetSession)
+	if e != nil {
+		fmt.Printf("FATAL: %s, terminating emulation..\n", e.Error())
+		pause()
+		return exitFailed
+	}
+	lastResult := batch.LastResult
+	if batch.Halted {
+		fmt.Println("\n+====[ HALTED DUE TO TOO MANY INFINITE LOOPS ]===+")
+	}
+
+	fmt.Println("Emulation completed in", time.Since(t).Seconds(), "seconds.")
+
+	eSlice := lastResult.Errors
+	if batch.NumSamples > 1 {
+		eSlice = nil
+	}
 
-		//performing the emulation
-		lastResult = Emulate(settings.TextStart, sysMemCopy, uint32(limit), eTol)
+	vet.DisplayGeneralResults(batch.NumSamples, int(batch.DIMin), int(batch.DIMax), len(lineMeta),
+		batch.TotalDI/float64(batch.NumSamples), eSlice, cfg.asmFile)
 
-		avgDI += float64(lastResult.DI)
-		if int(lastResult.DI) < dimin {
-			dimin = int(lastResult.DI)
+	if lastResult.Output != "" {
+		fmt.Println("\nConsole output of the last emulation:")
+		fmt.Print(lastResult.Output)
+		if !strings.HasSuffix(lastResult.Output, "\n") {
+			fmt.Println()
 		}
-		if int(lastResult.DI) > dimax {
-			dimax = int(lastResult.DI)
+		if lastResult.ExitCode != 0 {
+			fmt.Printf("The program exited with code %d.\n", lastResult.ExitCode)
 		}
+	}
+	for _, d := range lastResult.Devices {
+		if disp, ok := d.(*emu.Display); ok && disp.Output() != "" {
+			fmt.Println("\nDisplay output of the last emulation:")
+			fmt.Print(disp.Output())
+			if !strings.HasSuffix(disp.Output(), "\n") {
+				fmt.Println()
+			}
+		}
+	}
 
-		//checking health of output
-		if len(lastResult.Errors) > 0 && lastResult.Errors[len(lastResult.Errors)-1].EType == eRuntimeLimitExceeded {
-			numInf++
+	if cfg.bitmapPNG != "" || cfg.bitmapGIF != "" {
+		saveBitmap(lastResult, cfg)
+	}
 
-			if numInf > 10 {
-				//too many infinite loops
-				fmt.Println("\n+====[ HALTED DUE TO TOO MANY INFINITE LOOPS ]===+")
-				numSamples = i + 1
-				break
-			}
+	if cfg.traceOut != "" {
+		if e := vet.SaveTrace(cfg.traceOut, lastResult.Trace, lineMeta); e != nil {
+			fmt.Println("ERROR: " + e.Error())
+		} else {
+			fmt.Printf("Saved the trace of the last %d instructions of the last emulation to %s\n",
+				len(lastResult.Trace), cfg.traceOut)
 		}
+	}
 
-		if vetSession != nil {
-			vetSession.vetP1Fa21Interop(lastResult)
+	if cfg.branches {
+		vet.DisplayBranchAnalysis(batch.Branches, sysMem, lineMeta)
+	}
+
+	if cfg.profile {
+		if cfg.profileOut != "" {
+			if e := batch.Profile.Save(cfg.profileOut, cfg.asmFile, string(b), lineMeta); e != nil {
+				fmt.Println("ERROR: " + e.Error())
+			} else {
+				fmt.Println("Saved the execution profile to " + cfg.profileOut)
+			}
+		} else if !cfg.explorer {
+			fmt.Println("\n+====[ EXECUTION PROFILE ]====+")
+			_ = batch.Profile.WriteText(os.Stdout, string(b), lineMeta)
 		}
+	}
 
-		//updating user every 10%
-		if numSamples > 10000 && i%(numSamples/10) == 0 {
-			fmt.Printf("Progress: Completed %d%% (%d emulations)\n", i/(numSamples/100), i)
+	var coverage *vet.Coverage
+	if cfg.coverage {
+		coverage = vet.MeasureCoverage(batch.Profile, batch.Branches, sysMem, string(b), lineMeta)
+		vet.DisplayCoverage(coverage)
+		if cfg.coverageOut != "" {
+			if e := coverage.Save(cfg.coverageOut, cfg.asmFile); e != nil {
+				fmt.Println("ERROR: " + e.Error())
+			} else {
+				fmt.Println("Saved the coverage report to " + cfg.coverageOut)
+			}
 		}
 	}
 
-	fmt.Println("Emulation completed in", time.Since(t).Seconds(), "seconds.")
+	if batch.Calls != nil {
+		names := vet.FunctionNames(labels)
+		fmt.Println("\n+====[ CALL PROFILE ]====+")
+		_ = batch.Calls.WriteReport(os.Stdout, names)
+		if cfg.callsOut != "" {
+			if e := batch.Calls.SaveCollapsed(cfg.callsOut, names); e != nil {
+				fmt.Println("ERROR: " + e.Error())
+			} else {
+				fmt.Println("Saved the collapsed stacks of the call profile to " + cfg.callsOut)
+			}
+		}
+	}
 
-	eSlice := lastResult.Errors
-	if numSamples > 1 {
-		eSlice = nil
+	if vetSession != nil {
+		vetSession.DisplayResults()
 	}
 
-	displayGeneralResults(numSamples, dimin, dimax, len(lineMeta), avgDI/float64(numSamples), eSlice, asmFile)
+	if cfg.explorer {
+		explorer.Start(lastResult, vetSession, labels, lineMeta, &explorer.Program{
+			Memory:     sysMem,
+			StartAddr:  settings.TextStart,
+			Limit:      uint32(cfg.limit),
+			ETol:       cfg.eTol,
+			ISA:        cfg.isa,
+			Exceptions: hasHandler,
+			Handler:    settings.KTextStart,
+			Syscalls:   cfg.syscalls,
+			Input:      cfg.input,
+			Devices:    devices,
+			FileName:   cfg.asmFile,
+			Source:     string(b),
+			Profile:    batch.Profile,
+			Calls:      batch.Calls,
+			Coverage:   coverage,
+		})
+	}
 
-	if vetSession != nil {
-		vetSession.displayResults()
+	if batch.Halted {
+		return exitFailed
+	} else if vetSession != nil && vetSession.CorrectCount != vetSession.TotalCount {
+		return exitFailed
+	} else if vetSession == nil && len(lastResult.Errors) > 0 {
+		return exitFailed
 	}
 
-	startExplorer(lastResult, vetSession, labels, lineMeta)
+	return exitOK
 }
 
-func exit() {
+//saves the bitmap display of the result as a PNG and/or GIF, as requested by the config
+func saveBitmap(res emu.EmulationResult, cfg runConfig) {
+	for _, d := range res.Devices {
+		fb, ok := d.(*emu.Framebuffer)
+		if !ok {
+			continue
+		}
+
+		if cfg.bitmapPNG != "" {
+			if e := fb.SavePNG(cfg.bitmapPNG); e != nil {
+				fmt.Println("ERROR: Failed to save the bitmap display: " + e.Error())
+			} else {
+				fmt.Println("Saved the bitmap display of the last emulation to " + cfg.bitmapPNG)
+			}
+		}
+		if cfg.bitmapGIF != "" {
+			if e := fb.SaveGIF(cfg.bitmapGIF, res.DI); e != nil {
+				fmt.Println("ERROR: Failed to save the bitmap display animation: " + e.Error())
+			} else {
+				fmt.Println("Saved the bitmap display animation of the last emulation to " + cfg.bitmapGIF)
+			}
+		}
+	}
+}
+
+//waits for the user to acknowledge a message, only when running the wizard
+func pause() {
+	if reader == nil {
+		return
+	}
+
 	fmt.Println("Press enter to exit..")
 	_, _ = reader.ReadByte()
-	os.Exit(1)
+}
+
+func exit() {
+	pause()
+	os.Exit(exitFailed)
 }
@@ -1,11 +1,13 @@
-package main
+//Package projects holds the course assignments. Importing it registers them with the vet package
+package projects
 
 import (
 	"fmt"
 	"math/rand"
-	"os"
 	"strconv"
-	"time"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/vet"
 )
 
 type p1Rot int
@@ -26,10 +28,10 @@ type Project1 struct {
 	ReportedOffset   uint32    `json:"reportedOffset"`
 }
 
-func (p *Project1) genSquare() uint32 {
+func (p *Project1) genSquare(r *rand.Rand) uint32 {
 	var t uint32
 	for true {
-		t = uint32(rand.Intn(65536))
+		t = uint32(r.Intn(65536))
 
 		//testing for contiguous color, which is not allowed
 		for i := 0; 8 > i; i++ {
@@ -84,10 +86,10 @@ func (p *Project1) testSolution(square uint32) bool {
 	return false
 }
 
-func (p *Project1) genSolution() {
-	p.SolutionOffset = uint32(4 * rand.Intn(8))
-	p.SolutionFlipped = rand.Intn(2) == 0
-	p.SolutionRotation = p1Rot(rand.Intn(4))
+func (p *Project1) genSolution(r *rand.Rand) {
+	p.SolutionOffset = uint32(4 * r.Intn(8))
+	p.SolutionFlipped = r.Intn(2) == 0
+	p.SolutionRotation = p1Rot(r.Intn(4))
 
 	//flipping is always first, then rotation
 	sol := p.Reference
@@ -124,22 +126,12 @@ func (p *Project1) genSolution() {
 	}
 }
 
-func (inst *instance) swi582() {
-	//memory address in register $1
-	rand.Seed(time.Now().UnixNano())
-	if !inst.regInitialized(1) {
-		inst.reportError(eSoftwareInterruptParameter, "register $1 uninitialized for swi 582 call. $1 should hold the Reference memory pointer")
-	}
-
+func generateP1Scenario(r *rand.Rand) *Project1 {
 	p := new(Project1)
-	p.Reference = p.genSquare()
-	p.genSolution()
+	p.Reference = p.genSquare(r)
+	p.genSolution(r)
 	p.ReportedOffset = 0x12345678 //an arbitrary number to compare to if there was even an attempt at solving it
 
-	a := inst.regs[1]
-	inst.memWrite(a, p.Reference, 0xFFFFFFFF)
-	inst.memWrite(a+4+p.SolutionOffset, p.Candidates[p.SolutionOffset/4], 0xFFFFFFFF)
-
 	//generating dummy squares
 	for i := 0; 8 > i; i++ {
 		if int(p.SolutionOffset/4) == i {
@@ -149,70 +141,114 @@ func (inst *instance) swi582() {
 		watchdog := 0
 
 		for true {
-			t := p.genSquare()
+			t := p.genSquare(r)
 			if !p.testSolution(t) {
 				p.Candidates[i] = t
-				inst.memWrite(a+uint32(i)*4+4, t, 0xFFFFFFFF)
 				break
 			}
 			watchdog++
 			if watchdog > 1000 {
 				watchdog = 0
 				fmt.Println("Randomization watchdog intervened")
-				rand.Seed(time.Now().UnixNano())
+				//the generator is not reseeded so that the scenario stays reproducible from the emulation seed
 			}
 		}
 	}
 
-	inst.swiContext = p
+	return p
+}
+
+func swi582(inst *emu.Machine) {
+	//memory address in register $1
+	var a uint32
+	if !inst.RegInitialized(1) {
+		inst.ReportError(emu.ESoftwareInterruptParameter, "register $1 uninitialized for swi 582 call. $1 should hold the Reference memory pointer")
+	} else {
+		a = inst.RegAccess(1)
+	}
+
+	p := vet.NewScenario(inst, p1Assignment{}).(*Project1)
+
+	inst.MemWrite(a, p.Reference, 0xFFFFFFFF)
+	for i := 0; 8 > i; i++ {
+		inst.MemWrite(a+uint32(i)*4+4, p.Candidates[i], 0xFFFFFFFF)
+	}
 }
 
-func (inst *instance) swi583() {
+func swi583(inst *emu.Machine) {
 	//getting project info
 	var p *Project1
-	p, ok := inst.swiContext.(*Project1)
+	p, ok := inst.SWIContext().(*Project1)
 	if !ok {
-		inst.reportError(eInvalidSoftwareInterrupt, "cannot use swi 583 with the previous swi call(s)")
+		inst.ReportError(emu.EInvalidSoftwareInterrupt, "cannot use swi 583 with the previous swi call(s)")
 		return
 	}
 
 	//offset in register $3
-	if !inst.regInitialized(1) {
-		inst.reportError(eSoftwareInterruptParameter, "register $3 uninitialized for swi 583 call. "+
+	if !inst.RegInitialized(3) {
+		inst.ReportError(emu.ESoftwareInterruptParameter, "register $3 uninitialized for swi 583 call. "+
 			"$3 should hold the byte offset of the solution from the first candidate")
 	}
 
-	p.ReportedOffset = inst.regAccess(3)
+	p.ReportedOffset = inst.RegAccess(3)
 	if p.ReportedOffset > 28 || p.ReportedOffset%4 != 0 {
-		inst.reportError(eSoftwareInterruptParameterValue, "%h is an invalid solution for swi 583. Must be in [0, 28] and word aligned (multiple of four)")
+		inst.ReportError(emu.ESoftwareInterruptParameterValue, "%d is an invalid solution for swi 583. Must be in [0, 28] and word aligned (multiple of four)", p.ReportedOffset)
 		return
 	}
 
 	//storing solution
-	inst.regWrite(6, p.SolutionOffset)
+	inst.RegWrite(6, p.SolutionOffset)
 }
 
-func (v *VetSession) vetP1Interop(result EmulationResult) {
-	v.TotalCount++
+type p1Assignment struct{}
+
+func init() {
+	vet.Register(p1Assignment{})
+}
+
+func (p1Assignment) ID() string {
+	return "P1Legacy"
+}
 
+func (p1Assignment) Name() string {
+	return "Project 1 (legacy)"
+}
+
+func (p1Assignment) SoftwareInterrupts() map[int]emu.SoftwareInterrupt {
+	return map[int]emu.SoftwareInterrupt{
+		582: swi582,
+		583: swi583,
+	}
+}
+
+func (p1Assignment) GenerateScenario(r *rand.Rand) interface{} {
+	return generateP1Scenario(r)
+}
+
+func (p1Assignment) Grade(result emu.EmulationResult) (vet.Grade, error) {
 	p, ok := result.SWIContext.(*Project1)
 	if !ok {
-		//fatal error, software interrupts not called for the vet case
-		fmt.Println("FATAL: Software interrupt swi 582 not called for the P1 vet, terminating emulation..")
-		os.Exit(1)
+		//software interrupts not called for the vet case
+		return vet.Grade{}, fmt.Errorf("software interrupt swi 582 not called")
+	}
+
+	grade := vet.Grade{
+		Correct: p.ReportedOffset == p.SolutionOffset,
 	}
 
 	if p.ReportedOffset == 0x12345678 {
 		//no guess was made
-		result.Errors = append(result.Errors, RuntimeError{
-			EType:   eNoAnswerReported,
+		grade.Errors = append(grade.Errors, emu.RuntimeError{
+			EType:   emu.ENoAnswerReported,
 			Message: "No call was made to swi 583 ",
 		})
 	}
-	if p.ReportedOffset == p.SolutionOffset {
-		//correct
-		v.CorrectCount++
-	}
+
+	return grade, nil
+}
+
+func (p1Assignment) Categorize(result emu.EmulationResult) string {
+	p := result.SWIContext.(*Project1)
 
 	//create test case string
 	rotStr := ""
@@ -237,31 +273,5 @@ func (v *VetSession) vetP1Interop(result EmulationResult) {
 
 	tCase := "P1-" + rotStr + "CW-" + flipStr + "-" + strconv.Itoa(int(p.SolutionOffset)) + "offset"
 
-	tcs, ok := v.TestCases[tCase]
-	if ok {
-		ef := tcs.ErrorsFrequency
-		addVetErrors(result.Errors, ef)
-		v.TestCases[tCase].TotalErrors = tcs.TotalErrors + len(result.Errors)
-		v.TestCases[tCase].ErrorsFrequency = ef
-		if p.ReportedOffset == p.SolutionOffset {
-			v.TestCases[tCase].Successes++
-		} else {
-			v.TestCases[tCase].Fails++
-			v.addVetFailedSnap(result, tCase)
-		}
-	} else {
-		ef := make(map[int]int)
-		ef = addVetErrors(result.Errors, ef)
-		v.TestCases[tCase] = new(VetTestCase)
-		v.TestCases[tCase].ErrorsFrequency = ef
-		v.TestCases[tCase].TotalErrors = len(result.Errors)
-		if p.ReportedOffset == p.SolutionOffset {
-			v.TestCases[tCase].Successes = 1
-			v.TestCases[tCase].Fails = 0
-		} else {
-			v.TestCases[tCase].Successes = 0
-			v.TestCases[tCase].Fails = 1
-			v.addVetFailedSnap(result, tCase)
-		}
-	}
+	return tCase
 }
@@ -1,4 +1,4 @@
-package main
+package projects
 
 import (
 	"fmt"
@@ -10,7 +10,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+	"github.com/danielcbailey/MIPSEmulator/vet"
 )
 
 type p1Obscurity int
@@ -99,15 +101,15 @@ func intAbs(a int) int {
 	return a
 }
 
-func (p *Project1Fa21) generatePart(color int, isTarget bool) bool {
-	width := rand.Intn(21) + 25
-	height := rand.Intn(21) + 25
+func (p *Project1Fa21) generatePart(r *rand.Rand, color int, isTarget bool) bool {
+	width := r.Intn(21) + 25
+	height := r.Intn(21) + 25
 
 	targetVertLines := width / 12
 	targetHorzLines := height / 12
 
-	tlx := rand.Intn(62-width) + 1
-	tly := rand.Intn(62-height) + 1
+	tlx := r.Intn(62-width) + 1
+	tly := r.Intn(62-height) + 1
 
 	hLines := make([]int, 0)
 	vLines := make([]int, 0)
@@ -116,7 +118,7 @@ func (p *Project1Fa21) generatePart(color int, isTarget bool) bool {
 	for i := 0; targetHorzLines > i; i++ {
 		for a := 0; 10 > a; a++ {
 			//Makes 10 attempts to generate a line, will abort if 10 attempts is exceeded
-			desiredY := rand.Intn(height) + tly
+			desiredY := r.Intn(height) + tly
 
 			//testing to see if it can place the line where it wants to
 			if !p.checkHAlloc(desiredY) && !p.checkHAlloc(desiredY-1) && !p.checkHAlloc(desiredY+1) {
@@ -135,7 +137,7 @@ func (p *Project1Fa21) generatePart(color int, isTarget bool) bool {
 	for i := 0; targetVertLines > i; i++ {
 		for a := 0; 10 > a; a++ {
 			//Makes 10 attempts to generate a line, will abort if 10 attempts is exceeded
-			desiredX := rand.Intn(width) + tlx
+			desiredX := r.Intn(width) + tlx
 
 			//testing to see if it can place the line where it wants to
 			if !p.checkVAlloc(desiredX) && !p.checkVAlloc(desiredX-1) && !p.checkVAlloc(desiredX+1) {
@@ -237,7 +239,7 @@ func (p *Project1Fa21) generatePart(color int, isTarget bool) bool {
 	return true
 }
 
-func (p *Project1Fa21) generatePile() bool {
+func (p *Project1Fa21) generatePile(r *rand.Rand) bool {
 	//must generate what colors are generated from bottom to top
 	//each color is unique to a part, so once a color is put in a position, it cannot be used again
 
@@ -248,7 +250,7 @@ func (p *Project1Fa21) generatePile() bool {
 
 	for i := 0; 7 > i; i++ {
 		for true {
-			c := rand.Intn(7) + 1
+			c := r.Intn(7) + 1
 			unique := true
 			for j := 0; j < i; j++ {
 				if colors[j] == c {
@@ -274,7 +276,7 @@ func (p *Project1Fa21) generatePile() bool {
 
 	//now to generate the parts
 	for _, v := range colors {
-		if !p.generatePart(v, v == int(p.TargetColor)) {
+		if !p.generatePart(r, v, v == int(p.TargetColor)) {
 			//must redo the generation
 			return false
 		}
@@ -368,17 +370,11 @@ func (p *Project1Fa21) validatePile() bool {
 	return true
 }
 
-func (inst *instance) swi598() {
-	//memory address in register $1
-	if !inst.regInitialized(1) {
-		inst.reportError(eSoftwareInterruptParameter, "register $1 uninitialized for swi 582 call. $1 should hold the Pile memory pointer")
-	}
-
+func generateP1Fa21Scenario(r *rand.Rand) *Project1Fa21 {
 	p := new(Project1Fa21)
 	p.ReportedAnswer = 0x12345678
 
-	p.TargetColor = uint32(rand.Intn(7) + 1)
-	inst.regWrite(3, p.TargetColor)
+	p.TargetColor = uint32(r.Intn(7) + 1)
 
 	//generating field
 	for i := 0; true; i++ {
@@ -386,10 +382,10 @@ func (inst *instance) swi598() {
 			i = 0
 			//Watchdog to prevent infinite field generation in extreme edge case
 			fmt.Println("Randomization watchdog intervened")
-			rand.Seed(time.Now().UnixNano())
+			//the generator is not reseeded so that the scenario stays reproducible from the emulation seed
 		}
 
-		if !p.generatePile() {
+		if !p.generatePile(r) {
 			//must try again, it failed to generate a valid field
 			continue
 		}
@@ -404,64 +400,102 @@ func (inst *instance) swi598() {
 		break
 	}
 
-	memLoc := inst.regAccess(1)
+	return p
+}
+
+func swi598(inst *emu.Machine) {
+	//memory address in register $1
+	if !inst.RegInitialized(1) {
+		inst.ReportError(emu.ESoftwareInterruptParameter, "register $1 uninitialized for swi 598 call. $1 should hold the Pile memory pointer")
+	}
+
+	p := vet.NewScenario(inst, p1Fa21Assignment{}).(*Project1Fa21)
+	inst.RegWrite(3, p.TargetColor)
+
+	memLoc := inst.RegAccess(1)
 
 	//storing pile in memory
 	for i := 0; 1024 > i; i++ {
-		inst.memWrite(memLoc+uint32(i)*4, p.Pile[i], 0xFFFFFFFF)
+		inst.MemWrite(memLoc+uint32(i)*4, p.Pile[i], 0xFFFFFFFF)
 	}
-
-	inst.swiContext = p
 }
 
-func (inst *instance) swi599() {
+func swi599(inst *emu.Machine) {
 	//getting project info
 	var p *Project1Fa21
-	p, ok := inst.swiContext.(*Project1Fa21)
+	p, ok := inst.SWIContext().(*Project1Fa21)
 	if !ok {
-		inst.reportError(eInvalidSoftwareInterrupt, "cannot use swi 599 with the previous swi call(s)")
+		inst.ReportError(emu.EInvalidSoftwareInterrupt, "cannot use swi 599 with the previous swi call(s)")
 		return
 	}
 
 	//offset in register $2
-	if !inst.regInitialized(1) {
-		inst.reportError(eSoftwareInterruptParameter, "register $2 uninitialized for swi 599 call. "+
+	if !inst.RegInitialized(2) {
+		inst.ReportError(emu.ESoftwareInterruptParameter, "register $2 uninitialized for swi 599 call. "+
 			"$2 should hold the packed byte offsets of the top left and bottom right corners.")
 	}
 
-	p.ReportedAnswer = inst.regAccess(2)
+	p.ReportedAnswer = inst.RegAccess(2)
 	if (p.ReportedAnswer&0xFFFF) > 4096 || (p.ReportedAnswer>>16) > 4096 {
-		inst.reportError(eSoftwareInterruptParameterValue, "%h is an invalid solution for swi 599. Reported "+
+		inst.ReportError(emu.ESoftwareInterruptParameterValue, "0x%X is an invalid solution for swi 599. Reported "+
 			"byte offsets must correspond to a pixel within the image, and the reported solution reports a number "+
 			"too large to be on the image.", p.ReportedAnswer)
 		return
 	}
 
 	//storing solution
-	inst.regWrite(3, p.Solution)
+	inst.RegWrite(3, p.Solution)
 }
 
-func (v *VetSession) vetP1Fa21Interop(result EmulationResult) {
-	v.TotalCount++
+type p1Fa21Assignment struct{}
 
+func init() {
+	vet.Register(p1Fa21Assignment{})
+}
+
+func (p1Fa21Assignment) ID() string {
+	return "P1"
+}
+
+func (p1Fa21Assignment) Name() string {
+	return "Project 1 Fall 2021"
+}
+
+func (p1Fa21Assignment) SoftwareInterrupts() map[int]emu.SoftwareInterrupt {
+	return map[int]emu.SoftwareInterrupt{
+		598: swi598,
+		599: swi599,
+	}
+}
+
+func (p1Fa21Assignment) GenerateScenario(r *rand.Rand) interface{} {
+	return generateP1Fa21Scenario(r)
+}
+
+func (p1Fa21Assignment) Grade(result emu.EmulationResult) (vet.Grade, error) {
 	p, ok := result.SWIContext.(*Project1Fa21)
 	if !ok {
-		//fatal error, software interrupts not called for the vet case
-		fmt.Println("FATAL: Software interrupt swi 598 not called for the P1 vet, terminating emulation..")
-		exit()
+		//software interrupts not called for the vet case
+		return vet.Grade{}, fmt.Errorf("software interrupt swi 598 not called")
+	}
+
+	grade := vet.Grade{
+		Correct: p.ReportedAnswer == p.Solution,
 	}
 
 	if p.ReportedAnswer == 0x12345678 {
 		//no guess was made
-		result.Errors = append(result.Errors, RuntimeError{
-			EType:   eNoAnswerReported,
+		grade.Errors = append(grade.Errors, emu.RuntimeError{
+			EType:   emu.ENoAnswerReported,
 			Message: "No call was made to swi 599 ",
 		})
 	}
-	if p.ReportedAnswer == p.Solution {
-		//correct
-		v.CorrectCount++
-	}
+
+	return grade, nil
+}
+
+func (p1Fa21Assignment) Categorize(result emu.EmulationResult) string {
+	p := result.SWIContext.(*Project1Fa21)
 
 	//create test case string
 	obsStr := ""
@@ -510,33 +544,7 @@ func (v *VetSession) vetP1Fa21Interop(result EmulationResult) {
 	tCase := "P1-" + obsStr + "-" + spaceStr + "-" + geoStr + "-" + strconv.Itoa(p.HorzLineCount) + "hLines-" +
 		strconv.Itoa(p.VertLineCount) + "vLines"
 
-	tcs, ok := v.TestCases[tCase]
-	if ok {
-		ef := tcs.ErrorsFrequency
-		addVetErrors(result.Errors, ef)
-		v.TestCases[tCase].TotalErrors = tcs.TotalErrors + len(result.Errors)
-		v.TestCases[tCase].ErrorsFrequency = ef
-		if p.ReportedAnswer == p.Solution {
-			v.TestCases[tCase].Successes++
-		} else {
-			v.TestCases[tCase].Fails++
-			v.addVetFailedSnap(result, tCase)
-		}
-	} else {
-		ef := make(map[int]int)
-		ef = addVetErrors(result.Errors, ef)
-		v.TestCases[tCase] = new(VetTestCase)
-		v.TestCases[tCase].ErrorsFrequency = ef
-		v.TestCases[tCase].TotalErrors = len(result.Errors)
-		if p.ReportedAnswer == p.Solution {
-			v.TestCases[tCase].Successes = 1
-			v.TestCases[tCase].Fails = 0
-		} else {
-			v.TestCases[tCase].Successes = 0
-			v.TestCases[tCase].Fails = 1
-			v.addVetFailedSnap(result, tCase)
-		}
-	}
+	return tCase
 }
 
 func drawBox(img *image.RGBA, x, y, width, height int, c color.Color) {
@@ -551,9 +559,11 @@ func drawBox(img *image.RGBA, x, y, width, height int, c color.Color) {
 	}
 }
 
-func genImageP1Fa21(res *EmulationResult) {
-	var context *Project1Fa21
-	context = res.SWIContext.(*Project1Fa21)
+func (p1Fa21Assignment) SaveImage(res *emu.EmulationResult) error {
+	context, ok := res.SWIContext.(*Project1Fa21)
+	if !ok {
+		return fmt.Errorf("the snapshot does not have a Project 1 Fall 2021 test case")
+	}
 
 	scale := 4
 	img := image.NewRGBA(image.Rect(0, 0, 64*scale, 64*scale))
@@ -671,22 +681,23 @@ func genImageP1Fa21(res *EmulationResult) {
 
 	f, err := os.Create("testCase.png")
 	if err != nil {
-		fmt.Println("Failed to create test case image: " + err.Error())
-		return
+		return fmt.Errorf("failed to create test case image: %s", err.Error())
 	}
 	defer f.Close()
 	err = png.Encode(f, img)
 	if err != nil {
-		fmt.Println("Failed to encode test case image: " + err.Error())
-		return
+		return fmt.Errorf("failed to encode test case image: %s", err.Error())
 	}
 
 	fmt.Println("Saved image of test case. Name: testCase.png")
+	return nil
 }
 
-func genFa21Project1Dump(res *EmulationResult) {
-	var context *Project1Fa21
-	context = res.SWIContext.(*Project1Fa21)
+func (p1Fa21Assignment) SaveDump(res *emu.EmulationResult) error {
+	context, ok := res.SWIContext.(*Project1Fa21)
+	if !ok {
+		return fmt.Errorf("the snapshot does not have a Project 1 Fall 2021 test case")
+	}
 
 	builder := strings.Builder{}
 	for i, v := range context.Pile {
@@ -702,9 +713,9 @@ func genFa21Project1Dump(res *EmulationResult) {
 		strconv.Itoa(int(context.Solution&0xFFFF)) + "_" + strconv.Itoa(int(context.TargetColor)) + ".txt"
 	e := ioutil.WriteFile(fName, []byte(builder.String()), 0644)
 	if e != nil {
-		fmt.Println("ERROR: Failed to save dump file:", e.Error())
-		return
+		return fmt.Errorf("failed to save dump file: %s", e.Error())
 	}
 
 	fmt.Println("Saved dump file. Name: " + fName)
+	return nil
 }
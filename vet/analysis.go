@@ -0,0 +1,258 @@
+//Package vet grades batches of emulations against the registered assignments
+package vet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+//TestCase tallies the results that were categorized into the same test case
+type TestCase struct {
+	Successes       int
+	Fails           int
+	ErrorsFrequency map[int]int //the key is the error type of the runtime error, the value is the amount of that type
+	TotalErrors     int
+}
+
+//Snapshot is a failed result kept for the explorer
+type Snapshot struct {
+	TestCase string
+	Snapshot emu.EmulationResult
+}
+
+//Session accumulates the vet results of an assignment. Results may be vetted concurrently
+type Session struct {
+	Assignment      string
+	CorrectCount    int
+	TotalCount      int
+	TestCases       map[string]*TestCase
+	FailedSnapshots []Snapshot //sorted by sample
+
+	spec     Assignment
+	seed     int64 //the seed of the batch, which decides the failed snapshots that are kept
+	retained map[string]*retention
+	lock     sync.Mutex //guards the session while samples are vetted concurrently
+}
+
+//the failures of a test case that are kept as snapshots, see addVetFailedSnap
+type retention struct {
+	keys        []float64 //of the kept failures, ascending
+	samples     []int     //of the kept failures, in the order of keys
+	minRejected float64   //the lowest key of the failures that are not kept
+}
+
+//only records a fraction of the failed snapshots, as they hold the whole memory.
+//every failure gets a random key derived from its sample, and the failures of a test case sorted by key are kept
+//while the nth key is below 0.75^n, so the probability exponentially decreases with more snapshots of the test case.
+//the snapshots kept only depend on the batch seed, not on the order the workers vet the samples in
+func (v *Session) addVetFailedSnap(result emu.EmulationResult, tc string) {
+	//the batch seed is inverted so the key is unrelated to the random numbers of the emulation itself
+	key := float64(uint64(emu.DeriveSeed(^v.seed, result.Sample))>>11) / (1 << 53)
+
+	r, ok := v.retained[tc]
+	if !ok {
+		r = &retention{minRejected: 1}
+		v.retained[tc] = r
+	}
+	if key >= r.minRejected {
+		//not capturing this failure, a failure with a lower key was not captured either
+		return
+	}
+	n := sort.SearchFloat64s(r.keys, key)
+	if key > math.Pow(0.75, float64(n)) {
+		r.minRejected = key
+		return
+	}
+
+	r.keys = append(r.keys, 0)
+	copy(r.keys[n+1:], r.keys[n:])
+	r.keys[n] = key
+	r.samples = append(r.samples, 0)
+	copy(r.samples[n+1:], r.samples[n:])
+	r.samples[n] = result.Sample
+
+	i := sort.Search(len(v.FailedSnapshots), func(i int) bool {
+		return v.FailedSnapshots[i].Snapshot.Sample > result.Sample
+	})
+	v.FailedSnapshots = append(v.FailedSnapshots, Snapshot{})
+	copy(v.FailedSnapshots[i+1:], v.FailedSnapshots[i:])
+	v.FailedSnapshots[i] = Snapshot{TestCase: tc, Snapshot: result}
+
+	//the failures with higher keys moved down by one, which may no longer keep them
+	for i := n + 1; len(r.keys) > i; i++ {
+		if r.keys[i] <= math.Pow(0.75, float64(i)) {
+			continue
+		}
+
+		r.minRejected = r.keys[i]
+		for _, sample := range r.samples[i:] {
+			v.removeFailedSnap(sample)
+		}
+		r.keys = r.keys[:i]
+		r.samples = r.samples[:i]
+		break
+	}
+}
+
+func (v *Session) removeFailedSnap(sample int) {
+	for i, s := range v.FailedSnapshots {
+		if s.Snapshot.Sample == sample {
+			v.FailedSnapshots = append(v.FailedSnapshots[:i], v.FailedSnapshots[i+1:]...)
+			return
+		}
+	}
+}
+
+func addVetErrors(errors []emu.RuntimeError, vErrors map[int]int) map[int]int {
+	for _, e := range errors {
+		v, ok := vErrors[e.EType]
+		if !ok {
+			vErrors[e.EType] = 1
+		} else {
+			vErrors[e.EType] = v + 1
+		}
+	}
+
+	return vErrors
+}
+
+//NewSession starts an empty vet session for the assignment, the seed is the one of the batch
+func NewSession(a Assignment, seed int64) *Session {
+	ret := new(Session)
+	ret.TestCases = make(map[string]*TestCase)
+	ret.Assignment = a.Name()
+	ret.spec = a
+	ret.seed = seed
+	ret.retained = make(map[string]*retention)
+	return ret
+}
+
+//Definition returns the assignment the session vets
+func (v *Session) Definition() Assignment {
+	return v.spec
+}
+
+//Vet grades the result with the session's assignment and tallies it into its test case
+//an error is returned if the result cannot be vetted at all, in which case nothing is tallied
+func (v *Session) Vet(result emu.EmulationResult) error {
+	//grading only reads the result, so the workers grade concurrently and only the tally is locked
+	grade, e := v.spec.Grade(result)
+	if e != nil {
+		return fmt.Errorf("%s for the %s vet", e.Error(), v.spec.ID())
+	}
+
+	result.Errors = append(result.Errors, grade.Errors...)
+	tCase := v.spec.Categorize(result)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.TotalCount++
+	if grade.Correct {
+		v.CorrectCount++
+	}
+
+	tcs, ok := v.TestCases[tCase]
+	if !ok {
+		tcs = new(TestCase)
+		tcs.ErrorsFrequency = make(map[int]int)
+		v.TestCases[tCase] = tcs
+	}
+
+	addVetErrors(result.Errors, tcs.ErrorsFrequency)
+	tcs.TotalErrors += len(result.Errors)
+	if grade.Correct {
+		tcs.Successes++
+	} else {
+		tcs.Fails++
+		v.addVetFailedSnap(result, tCase)
+	}
+
+	return nil
+}
+
+//DisplayResults prints the vet results by test case category
+func (v *Session) DisplayResults() {
+	avgErr := 0.0
+	for _, val := range v.TestCases {
+		avgErr += float64(val.TotalErrors)
+	}
+	avgErr /= float64(v.TotalCount)
+
+	fmt.Println("\n+====[ VET RESULTS ]====+")
+	fmt.Printf("Vet for %s.\n", v.Assignment)
+	fmt.Printf("Summary:\n")
+	fmt.Printf(" - Performed %d tests.\n", v.TotalCount)
+	fmt.Printf(" - Of those, %d were successful (%.3f%% success rate).\n", v.CorrectCount, float64(v.CorrectCount)/float64(v.TotalCount)*100)
+	fmt.Printf(" - For each evaluation, on average there were %.3f errors.\n", avgErr)
+
+	fmt.Printf("\nTest Cases (%d) (Organized into categories; categories are not mutually exclusive):\n", len(v.TestCases))
+	//category detection
+	//format is as such: assignment-cat1-cat2-cat3-...-catn
+	options := make(map[int]map[string]*TestCase)
+	for k, v := range v.TestCases {
+		categories := strings.Split(k, "-")
+		for i := 1; len(categories) > i; i++ {
+			c, ok := options[i]
+			if !ok {
+				c = make(map[string]*TestCase)
+				options[i] = c
+			}
+
+			cv, ok := c[categories[i]]
+			if !ok {
+				cv = new(TestCase)
+				cv.TotalErrors = 0
+				cv.Fails = 0
+				cv.Successes = 0
+				cv.ErrorsFrequency = make(map[int]int)
+				c[categories[i]] = cv
+			}
+
+			cv.Successes += v.Successes
+			cv.Fails += v.Fails
+			cv.TotalErrors += v.TotalErrors
+			for ek, ev := range v.ErrorsFrequency {
+				ec, ok := cv.ErrorsFrequency[ek]
+				if !ok {
+					cv.ErrorsFrequency[ek] = ev
+				} else {
+					ec += ev
+					cv.ErrorsFrequency[ek] = ec
+				}
+			}
+		}
+	}
+
+	for _, vi := range options {
+		for kj, vj := range vi {
+			fmt.Printf(" - %s: Successes: %d; Fails: %d; Error Count: %d\n", kj, vj.Successes, vj.Fails, vj.TotalErrors)
+			for ek, ef := range vj.ErrorsFrequency {
+				fmt.Printf("   + Error: %s; Count: %d (%.3f%%)\n", emu.DecodeErrorCode(ek), ef, float64(ef)/float64(vj.TotalErrors)*100)
+			}
+		}
+		fmt.Println("")
+	}
+}
+
+//DisplayGeneralResults prints the emulation statistics of a batch
+func DisplayGeneralResults(n, dimin, dimax, si int, avgdi float64, errors []emu.RuntimeError, fName string) {
+	fmt.Println("\n+====[ EMULATION RESULTS ]====+")
+	fmt.Printf("Emulation of %s.\n", fName)
+	fmt.Printf("Summary:\n")
+	fmt.Printf(" - Performed %d tests.\n", n)
+	fmt.Printf(" - %d SI; %5.2f average DI (min: %d, max: %d)\n", si, avgdi, dimin, dimax)
+
+	if errors != nil {
+		fmt.Printf(" - Total errors generated: %d\n", len(errors))
+		fmt.Printf("\nAll errors:\n")
+		for _, e := range errors {
+			fmt.Printf(" - %s; %s\n", emu.DecodeErrorCode(e.EType), e.Message)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package vet
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Assignments
+ * Each course project implements the Assignment interface and registers itself from an init function in its own file.
+ * The emulator core only knows about the software interrupts the registered assignments own, and the tally of vet
+ * results (see Session.Vet) is shared by all assignments.
+ */
+
+//Assignment is implemented by every course project that can be vetted
+type Assignment interface {
+	//the short name used to select the assignment, such as "P1". Is case-insensitive
+	ID() string
+
+	//the full name displayed in the vet results
+	Name() string
+
+	//the software interrupts owned by the assignment, keyed by the swi number
+	SoftwareInterrupts() map[int]emu.SoftwareInterrupt
+
+	//generates a random scenario (test case). The software interrupt that starts a test case calls NewScenario,
+	//which stores it in the emulation's SWI context
+	GenerateScenario(r *rand.Rand) interface{}
+
+	//grades the result of an emulation. An error is returned if the result cannot be graded at all,
+	//for example if the scenario was never generated
+	Grade(result emu.EmulationResult) (Grade, error)
+
+	//names the test case of a graded result, in the form "assignment-category1-category2-...-categoryN"
+	Categorize(result emu.EmulationResult) string
+}
+
+//Grade is the verdict of an assignment on a single result
+type Grade struct {
+	Correct bool
+	Errors  []emu.RuntimeError //errors found by grading, which are added to those of the emulation
+}
+
+//ImageExporter is optionally implemented by assignments that can render an image of a result's scenario
+type ImageExporter interface {
+	SaveImage(result *emu.EmulationResult) error
+}
+
+//DumpExporter is optionally implemented by assignments that can export a result's scenario to a dump file
+type DumpExporter interface {
+	SaveDump(result *emu.EmulationResult) error
+}
+
+var assignments = make(map[string]Assignment) //keyed by the lower-cased ID
+
+//Register makes the assignment available to Find and hands its software interrupts to the emulator
+//should only be called from init functions; panics if the assignment or one of its software interrupts is a duplicate
+func Register(a Assignment) {
+	id := strings.ToLower(a.ID())
+	if _, ok := assignments[id]; ok {
+		panic("assignment " + a.ID() + " is registered twice")
+	}
+
+	for code, handler := range a.SoftwareInterrupts() {
+		if e := emu.RegisterSoftwareInterrupt(code, handler); e != nil {
+			panic(fmt.Sprintf("assignment %s: %s", a.ID(), e.Error()))
+		}
+	}
+
+	assignments[id] = a
+}
+
+//NewScenario generates a scenario of the assignment with the emulation's random generator, so that it is reproducible
+//from the seed, and stores it in the SWI context. Called by the software interrupt that starts a test case
+func NewScenario(inst *emu.Machine, a Assignment) interface{} {
+	scenario := a.GenerateScenario(inst.Rand())
+	inst.SetSWIContext(scenario)
+	return scenario
+}
+
+//Find returns the assignment with the given ID, or nil if it is not known
+func Find(id string) Assignment {
+	return assignments[strings.ToLower(id)]
+}
+
+//List returns the registered assignments sorted by ID
+func List() []Assignment {
+	ret := make([]Assignment, 0, len(assignments))
+	for _, a := range assignments {
+		ret = append(ret, a)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID() < ret[j].ID()
+	})
+	return ret
+}
+
+//Describe lists the assignment options, for example "'P1' for Project 1 Fall 2021"
+func Describe() string {
+	var options []string
+	for _, a := range List() {
+		options = append(options, "'"+a.ID()+"' for "+a.Name())
+	}
+
+	return strings.Join(options, ", ")
+}
@@ -0,0 +1,212 @@
+package vet
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Batch emulation
+ * Runs many samples of the same assembled program concurrently. Each sample is emulated on its own copy of the
+ * assembled memory, so the workers share nothing but the statistics, which are merged under a lock.
+ *
+ * Every sample's seed is derived from the batch seed and the sample's index, so any single sample of a batch can be
+ * emulated again on its own (by setting FirstSample) and will produce the exact same result.
+ */
+
+const maxInfiniteLoops = 10 //the batch is halted once more samples than this exceed the runtime limit
+
+//BatchSettings describes the samples to emulate
+type BatchSettings struct {
+	StartAddr   uint32
+	NumSamples  int
+	FirstSample int //the index of the first sample, samples are numbered from FirstSample to FirstSample+NumSamples-1
+	Seed        int64
+	Limit       uint32
+	ETol        int
+	ISA         emu.ISA
+	Exceptions  bool   //enables the exception model, with faults vectoring to the Handler
+	Handler     uint32 //the address of the exception handler, the start of .ktext
+	Syscalls    bool   //enables the syscall services, which read from Input
+	Input       string
+	Devices     func() []emu.Device //creates the memory-mapped devices of a sample, may be nil
+	Profile     bool                //counts the executions of every instruction, see BatchResult.Profile
+	CallProfile bool                //keeps a shadow call stack, see BatchResult.Calls
+	Trace       int                 //the number of instructions kept in the trace of every sample, 0 for none
+	Workers     int                 //0 will use one worker per CPU
+	Progress    bool                //prints progress every 10% for large batches
+}
+
+//BatchResult holds the statistics of the emulated samples
+type BatchResult struct {
+	NumSamples int  //the number of samples actually emulated, may be smaller than requested if halted
+	Halted     bool //too many samples exceeded the runtime limit
+	DIMin      uint32
+	DIMax      uint32
+	TotalDI    float64
+	LastResult emu.EmulationResult //the result of the highest-numbered sample
+	Branches   BranchAnalysis      //the branch analysis of every sample added together
+	Profile    *Profile            //nil unless BatchSettings.Profile is set
+	Calls      *CallProfile        //nil unless BatchSettings.CallProfile is set
+}
+
+//RunBatch emulates the program in sysMem settings.NumSamples times, vetting each result if vSession is not nil
+//sysMem itself is never modified. An error is returned if a result could not be vetted, which stops the batch
+func RunBatch(sysMem emu.SystemMemory, settings BatchSettings, vSession *Session) (BatchResult, error) {
+	workers := settings.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > settings.NumSamples {
+		workers = settings.NumSamples
+	}
+
+	ret := BatchResult{
+		DIMin:    settings.Limit,
+		Branches: make(BranchAnalysis),
+	}
+	if settings.Profile {
+		ret.Profile = NewProfile()
+	}
+	if settings.CallProfile {
+		ret.Calls = NewCallProfile()
+	}
+	lastIndex := -1
+	numInf := 0
+	completed := 0
+	next := 0
+	var vetErr error
+
+	var lock sync.Mutex //guards everything above
+	var wg sync.WaitGroup
+
+	for w := 0; workers > w; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				//claiming the next sample
+				lock.Lock()
+				if ret.Halted || vetErr != nil || next >= settings.NumSamples {
+					lock.Unlock()
+					return
+				}
+				i := next
+				next++
+				lock.Unlock()
+
+				//performing the emulation on a copy of the memory
+				sample := settings.FirstSample + i
+				machine := emu.New(settings.StartAddr, sysMem.Clone(), settings.Limit, settings.ETol,
+					emu.DeriveSeed(settings.Seed, sample))
+				machine.SetISA(settings.ISA)
+				if settings.Exceptions {
+					machine.EnableExceptions(settings.Handler)
+				}
+				if settings.Syscalls {
+					machine.EnableSyscalls(settings.Input)
+				}
+				if settings.Profile {
+					machine.EnableProfile()
+				}
+				if settings.CallProfile {
+					machine.EnableCallProfile()
+				}
+				if settings.Trace > 0 {
+					machine.EnableTrace(settings.Trace)
+				}
+				if e := attachDevices(machine, settings.Devices); e != nil {
+					lock.Lock()
+					if vetErr == nil {
+						vetErr = e
+					}
+					lock.Unlock()
+					return
+				}
+				result := machine.Run()
+				result.Sample = sample
+
+				lock.Lock()
+				if ret.Halted {
+					//another worker halted the batch while this sample was running, so it is discarded
+					lock.Unlock()
+					return
+				}
+
+				ret.TotalDI += float64(result.DI)
+				if result.DI < ret.DIMin {
+					ret.DIMin = result.DI
+				}
+				if result.DI > ret.DIMax {
+					ret.DIMax = result.DI
+				}
+				if i > lastIndex {
+					lastIndex = i
+					ret.LastResult = result
+				}
+				ret.Branches.Add(result.BranchAnalysis)
+				if ret.Profile != nil {
+					ret.Profile.Add(result.Profile)
+				}
+				if ret.Calls != nil {
+					ret.Calls.Add(result.CallTree)
+				}
+				completed++
+
+				//checking health of output
+				if len(result.Errors) > 0 && result.Errors[len(result.Errors)-1].EType == emu.ERuntimeLimitExceeded {
+					numInf++
+
+					if numInf > maxInfiniteLoops {
+						//too many infinite loops, this sample counts towards the statistics but is not vetted
+						ret.Halted = true
+						lock.Unlock()
+						return
+					}
+				}
+
+				//updating user every 10%
+				if settings.Progress && settings.NumSamples > 10000 && completed%(settings.NumSamples/10) == 0 {
+					fmt.Printf("Progress: Completed %d%% (%d emulations)\n", completed/(settings.NumSamples/100), completed)
+				}
+				lock.Unlock()
+
+				if vSession != nil {
+					//the session has its own lock
+					if e := vSession.Vet(result); e != nil {
+						lock.Lock()
+						if vetErr == nil {
+							vetErr = e
+						}
+						lock.Unlock()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	ret.NumSamples = completed
+	return ret, vetErr
+}
+
+//attaches the devices created by the factory, which may be nil
+func attachDevices(machine *emu.Machine, factory func() []emu.Device) error {
+	if factory == nil {
+		return nil
+	}
+
+	for _, d := range factory() {
+		if e := machine.AttachDevice(d); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package vet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Branch analysis
+ * The emulator counts how many times every conditional branch is executed and taken. Over a batch, the counts of the
+ * samples are added together, which shows the branches that are never taken, always taken or never executed at all:
+ * dead paths, or loops that are structured so that their branch is mispredicted.
+ */
+
+//BranchCounts is how many times a conditional branch was executed and taken, over any number of emulations
+type BranchCounts struct {
+	Executed uint64
+	Taken    uint64
+}
+
+//BranchAnalysis holds the counts of the conditional branches, keyed by their address
+type BranchAnalysis map[uint32]BranchCounts
+
+//Add adds the branch analysis of an emulation to the counts
+func (b BranchAnalysis) Add(info map[uint32]emu.BranchInfo) {
+	for addr, i := range info {
+		c := b[addr]
+		c.Executed += uint64(i.TotalCount)
+		c.Taken += uint64(i.BranchCount)
+		b[addr] = c
+	}
+}
+
+//DisplayBranchAnalysis displays the counts of every conditional branch in the program by line. The memory is the
+//assembled program, used to find the branches that were never executed
+func DisplayBranchAnalysis(b BranchAnalysis, mem emu.SystemMemory, lineMeta map[uint32]asm.InputLine) {
+	var addrs []uint32
+	for addr := range lineMeta {
+		if instr, ok := mem.Read(addr); ok && emu.IsConditionalBranch(instr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i] < addrs[j]
+	})
+
+	fmt.Println("\n+====[ BRANCH ANALYSIS ]====+")
+	if len(addrs) == 0 {
+		fmt.Println("The program has no conditional branches.")
+		return
+	}
+
+	numDead, numNever, numAlways := 0, 0, 0
+	for _, addr := range addrs {
+		l := lineMeta[addr]
+		c := b[addr]
+		desc := fmt.Sprintf(" - line %d \"%s\": ", l.LineNumber, strings.Trim(l.Contents, " \t"))
+
+		if c.Executed == 0 {
+			numDead++
+			fmt.Println(desc + "never executed")
+		} else if c.Taken == 0 {
+			numNever++
+			fmt.Printf("%snever taken (executed %d times)\n", desc, c.Executed)
+		} else if c.Taken == c.Executed {
+			numAlways++
+			fmt.Printf("%salways taken (executed %d times)\n", desc, c.Executed)
+		} else {
+			fmt.Printf("%staken %d of %d times (%.1f%%)\n", desc, c.Taken, c.Executed,
+				float64(c.Taken)*100/float64(c.Executed))
+		}
+	}
+
+	fmt.Printf("Summary: %d conditional branches, %d never executed, %d never taken and %d always taken.\n",
+		len(addrs), numDead, numNever, numAlways)
+}
@@ -0,0 +1,286 @@
+package vet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Call profile
+ * The calling context trees of the samples (see emu.CallNode) added together. From them come a flat profile of the
+ * instructions executed in each function, exclusive of its callees and inclusive of them, a call graph of who calls
+ * whom, and the collapsed stacks read by flame graph tools such as flamegraph.pl and speedscope.
+ *
+ * Functions are named by the label at their address. A function without a label is named by its address.
+ */
+
+//CallProfile is the calling context tree of every sample added together
+type CallProfile struct {
+	Root    *emu.CallNode //nil until a tree is added
+	Samples int
+}
+
+//FunctionProfile is a line of the flat profile
+type FunctionProfile struct {
+	Function  uint32
+	Calls     uint64
+	Exclusive uint64 //the instructions executed in the function itself
+	Inclusive uint64 //the instructions executed in the function and its callees, recursive calls counted once
+}
+
+//CallEdge is how many times a function called another
+type CallEdge struct {
+	Caller, Callee uint32
+	Calls          uint64
+}
+
+//NewCallProfile creates an empty call profile
+func NewCallProfile() *CallProfile {
+	return &CallProfile{}
+}
+
+//Add adds the calling context tree of one emulation to the profile
+func (p *CallProfile) Add(root *emu.CallNode) {
+	if root == nil {
+		return
+	}
+	if p.Root == nil {
+		p.Root = emu.NewCallNode(root.Function, nil)
+	}
+
+	mergeCallNode(p.Root, root)
+	p.Samples++
+}
+
+func mergeCallNode(dst, src *emu.CallNode) {
+	dst.Calls += src.Calls
+	dst.Self += src.Self
+	for f, c := range src.Children {
+		mergeCallNode(dst.Child(f), c)
+	}
+}
+
+//Total returns the number of instructions executed over every sample
+func (p *CallProfile) Total() uint64 {
+	if p.Root == nil {
+		return 0
+	}
+
+	return p.Root.Total()
+}
+
+//Functions returns the flat profile, ordered by the exclusive count from the highest
+func (p *CallProfile) Functions() []FunctionProfile {
+	if p.Root == nil {
+		return nil
+	}
+
+	funcs := make(map[uint32]*FunctionProfile)
+	active := make(map[uint32]int) //the functions on the path to the node, so recursion is only counted once
+	var walk func(n *emu.CallNode) uint64
+	walk = func(n *emu.CallNode) uint64 {
+		f, ok := funcs[n.Function]
+		if !ok {
+			f = &FunctionProfile{Function: n.Function}
+			funcs[n.Function] = f
+		}
+		f.Calls += n.Calls
+		f.Exclusive += n.Self
+
+		active[n.Function]++
+		total := n.Self
+		for _, c := range n.Children {
+			total += walk(c)
+		}
+		active[n.Function]--
+
+		if active[n.Function] == 0 {
+			f.Inclusive += total
+		}
+		return total
+	}
+	walk(p.Root)
+
+	ret := make([]FunctionProfile, 0, len(funcs))
+	for _, f := range funcs {
+		ret = append(ret, *f)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Exclusive != ret[j].Exclusive {
+			return ret[i].Exclusive > ret[j].Exclusive
+		}
+		return ret[i].Function < ret[j].Function
+	})
+
+	return ret
+}
+
+//Edges returns the call graph, ordered by the caller and the callee
+func (p *CallProfile) Edges() []CallEdge {
+	if p.Root == nil {
+		return nil
+	}
+
+	calls := make(map[[2]uint32]uint64)
+	var walk func(n *emu.CallNode)
+	walk = func(n *emu.CallNode) {
+		for f, c := range n.Children {
+			calls[[2]uint32{n.Function, f}] += c.Calls
+			walk(c)
+		}
+	}
+	walk(p.Root)
+
+	ret := make([]CallEdge, 0, len(calls))
+	for k, c := range calls {
+		ret = append(ret, CallEdge{Caller: k[0], Callee: k[1], Calls: c})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Caller != ret[j].Caller {
+			return ret[i].Caller < ret[j].Caller
+		}
+		return ret[i].Callee < ret[j].Callee
+	})
+
+	return ret
+}
+
+//FunctionNames names the addresses by their labels. When an address has several labels, the first alphabetically is
+//used so the names are the same from run to run
+func FunctionNames(labels map[string]uint32) map[uint32]string {
+	ret := make(map[uint32]string)
+	for l, addr := range labels {
+		if n, ok := ret[addr]; !ok || l < n {
+			ret[addr] = l
+		}
+	}
+
+	return ret
+}
+
+func functionName(names map[uint32]string, addr uint32) string {
+	if n, ok := names[addr]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("0x%08X", addr)
+}
+
+func percentOf(count, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(count) * 100 / float64(total)
+}
+
+//WriteReport writes the flat profile followed by the call graph
+func (p *CallProfile) WriteReport(w io.Writer, names map[uint32]string) error {
+	total := p.Total()
+	funcs := p.Functions()
+	_, e := fmt.Fprintf(w, "Call profile of %d sample(s), %d instructions in total.\n%10s %12s %7s %12s %7s  %s\n",
+		p.Samples, total, "Calls", "Self", "Self %", "Total", "Total %", "Function")
+	if e != nil {
+		return e
+	}
+
+	for _, f := range funcs {
+		_, e = fmt.Fprintf(w, "%10d %12d %6.2f%% %12d %6.2f%%  %s\n", f.Calls, f.Exclusive,
+			percentOf(f.Exclusive, total), f.Inclusive, percentOf(f.Inclusive, total), functionName(names, f.Function))
+		if e != nil {
+			return e
+		}
+	}
+
+	edges := p.Edges()
+	if _, e = fmt.Fprintln(w, "\nCall graph:"); e != nil {
+		return e
+	}
+	if len(edges) == 0 {
+		_, e = fmt.Fprintln(w, "No function was called.")
+		return e
+	}
+
+	for _, f := range funcs {
+		var callers, callees []CallEdge
+		for _, edge := range edges {
+			if edge.Callee == f.Function {
+				callers = append(callers, edge)
+			}
+			if edge.Caller == f.Function {
+				callees = append(callees, edge)
+			}
+		}
+		if len(callers) == 0 && len(callees) == 0 {
+			continue
+		}
+
+		if _, e = fmt.Fprintln(w, functionName(names, f.Function)); e != nil {
+			return e
+		}
+		for _, edge := range callers {
+			_, e = fmt.Fprintf(w, "   called by %s (%d times)\n", functionName(names, edge.Caller), edge.Calls)
+			if e != nil {
+				return e
+			}
+		}
+		for _, edge := range callees {
+			_, e = fmt.Fprintf(w, "   calls %s (%d times)\n", functionName(names, edge.Callee), edge.Calls)
+			if e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+//WriteCollapsed writes the collapsed stacks, a line per path of calls with the instructions executed at its end,
+//such as "main;sort;swap 1200"
+func (p *CallProfile) WriteCollapsed(w io.Writer, names map[uint32]string) error {
+	if p.Root == nil {
+		return nil
+	}
+
+	var lines []string
+	var walk func(n *emu.CallNode, stack string)
+	walk = func(n *emu.CallNode, stack string) {
+		if stack != "" {
+			stack += ";"
+		}
+		stack += functionName(names, n.Function)
+		if n.Self > 0 {
+			lines = append(lines, fmt.Sprintf("%s %d", stack, n.Self))
+		}
+		for _, c := range n.Children {
+			walk(c, stack)
+		}
+	}
+	walk(p.Root, "")
+	sort.Strings(lines)
+
+	for _, l := range lines {
+		if _, e := fmt.Fprintln(w, l); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+//SaveCollapsed writes the collapsed stacks to the file
+func (p *CallProfile) SaveCollapsed(fileName string, names map[uint32]string) error {
+	f, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the collapsed stacks: %s", e.Error())
+	}
+	defer f.Close()
+
+	if e = p.WriteCollapsed(f, names); e != nil {
+		return fmt.Errorf("failed to write the collapsed stacks: %s", e.Error())
+	}
+	return nil
+}
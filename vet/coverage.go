@@ -0,0 +1,236 @@
+package vet
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Code coverage
+ * Which lines of the program were executed by any of the samples of a batch, from the execution profile, and which
+ * directions of its conditional branches were taken, from the branch analysis. A line is covered when all of its
+ * instructions were executed and its branch went both ways, partially covered when only some of that happened, and
+ * uncovered when it was never reached: a path the randomized test cases never exercise.
+ */
+
+//CoverageStatus is how much of a line was covered
+type CoverageStatus int
+
+const (
+	NotCode CoverageStatus = iota //the line has no instructions
+	Uncovered
+	PartiallyCovered
+	Covered
+)
+
+//LineCoverage is the coverage of a line of the source
+type LineCoverage struct {
+	LineNumber   int
+	Contents     string
+	Instructions int //the number of instructions the line assembles to
+	Executed     int //the number of those instructions executed at least once
+	Directions   int //two for every conditional branch of the line, taken and not taken
+	Taken        int //the number of those directions that were followed at least once
+}
+
+//Status returns how much of the line was covered
+func (l LineCoverage) Status() CoverageStatus {
+	if l.Instructions == 0 {
+		return NotCode
+	} else if l.Executed == 0 {
+		return Uncovered
+	} else if l.Executed < l.Instructions || l.Taken < l.Directions {
+		return PartiallyCovered
+	}
+
+	return Covered
+}
+
+//Coverage is the coverage of every line of the source over a batch
+type Coverage struct {
+	Lines   []LineCoverage
+	Samples int
+}
+
+//CoverageTotals are the totals of the coverage over the whole program
+type CoverageTotals struct {
+	Lines, LinesCovered               int //only lines with instructions, partially covered lines are not counted
+	Instructions, InstructionsCovered int
+	Directions, DirectionsCovered     int
+}
+
+//MeasureCoverage joins the profile and branch analysis of a batch with every line of the source. The memory is the
+//assembled program, used to find the conditional branches
+func MeasureCoverage(p *Profile, b BranchAnalysis, mem emu.SystemMemory, source string,
+	lineMeta map[uint32]asm.InputLine) *Coverage {
+	lines := strings.Split(source, "\n")
+	ret := &Coverage{
+		Lines:   make([]LineCoverage, len(lines)),
+		Samples: p.Samples,
+	}
+	for i, l := range lines {
+		ret.Lines[i] = LineCoverage{
+			LineNumber: i + 1,
+			Contents:   strings.TrimRight(l, "\r"),
+		}
+	}
+
+	for addr, l := range lineMeta {
+		if l.LineNumber < 1 || l.LineNumber > len(ret.Lines) {
+			continue
+		}
+		line := &ret.Lines[l.LineNumber-1]
+		line.Instructions++
+		if p.Counts[addr] > 0 {
+			line.Executed++
+		}
+
+		if instr, ok := mem.Read(addr); ok && emu.IsConditionalBranch(instr) {
+			c := b[addr]
+			line.Directions += 2
+			if c.Taken > 0 {
+				line.Taken++
+			}
+			if c.Executed > c.Taken {
+				line.Taken++
+			}
+		}
+	}
+
+	return ret
+}
+
+//Totals adds up the coverage of every line
+func (c *Coverage) Totals() CoverageTotals {
+	var ret CoverageTotals
+	for _, l := range c.Lines {
+		if l.Instructions == 0 {
+			continue
+		}
+		ret.Lines++
+		if l.Status() == Covered {
+			ret.LinesCovered++
+		}
+		ret.Instructions += l.Instructions
+		ret.InstructionsCovered += l.Executed
+		ret.Directions += l.Directions
+		ret.DirectionsCovered += l.Taken
+	}
+
+	return ret
+}
+
+func coveragePercent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return float64(covered) * 100 / float64(total)
+}
+
+//describes why a line is not fully covered
+func (l LineCoverage) describe() string {
+	if l.Executed == 0 {
+		return "never executed"
+	}
+
+	var parts []string
+	if l.Executed < l.Instructions {
+		parts = append(parts, fmt.Sprintf("%d of %d instructions executed", l.Executed, l.Instructions))
+	}
+	if l.Taken < l.Directions {
+		parts = append(parts, fmt.Sprintf("%d of %d branch directions taken", l.Taken, l.Directions))
+	}
+	return strings.Join(parts, ", ")
+}
+
+//DisplayCoverage displays the totals of the coverage and the lines that were not fully covered
+func DisplayCoverage(c *Coverage) {
+	t := c.Totals()
+	fmt.Println("\n+====[ CODE COVERAGE ]====+")
+	fmt.Printf("Coverage of %d sample(s):\n", c.Samples)
+	fmt.Printf(" - Lines: %d of %d fully covered (%.1f%%)\n", t.LinesCovered, t.Lines,
+		coveragePercent(t.LinesCovered, t.Lines))
+	fmt.Printf(" - Instructions: %d of %d executed (%.1f%%)\n", t.InstructionsCovered, t.Instructions,
+		coveragePercent(t.InstructionsCovered, t.Instructions))
+	fmt.Printf(" - Branch directions: %d of %d taken (%.1f%%)\n", t.DirectionsCovered, t.Directions,
+		coveragePercent(t.DirectionsCovered, t.Directions))
+
+	if t.LinesCovered == t.Lines {
+		fmt.Println("Every line was fully covered.")
+		return
+	}
+
+	fmt.Println("Lines not fully covered:")
+	for _, l := range c.Lines {
+		if s := l.Status(); s == Uncovered || s == PartiallyCovered {
+			fmt.Printf(" - line %d \"%s\": %s\n", l.LineNumber, strings.Trim(l.Contents, " \t"), l.describe())
+		}
+	}
+}
+
+//WriteHTML writes the source as an HTML page, with the covered lines in green, the partially covered lines in yellow
+//and the uncovered lines in red
+func (c *Coverage) WriteHTML(w io.Writer, title string) error {
+	t := c.Totals()
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Code coverage of %s</title>\n", html.EscapeString(title))
+	sb.WriteString("<style>\nbody { font-family: sans-serif; }\ntable { border-collapse: collapse; font-family: monospace; }\n" +
+		"td { padding: 0 8px; white-space: pre; }\ntd.n { text-align: right; color: #555; }\n" +
+		"tr.covered { background: #c8f0c8; }\ntr.partial { background: #f8e8a0; }\ntr.uncovered { background: #f8c0c0; }\n" +
+		"</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h2>Code coverage of %s</h2>\n<p>%d sample(s).</p>\n<ul>\n", html.EscapeString(title), c.Samples)
+	fmt.Fprintf(&sb, "<li>Lines: %d of %d fully covered (%.1f%%)</li>\n", t.LinesCovered, t.Lines,
+		coveragePercent(t.LinesCovered, t.Lines))
+	fmt.Fprintf(&sb, "<li>Instructions: %d of %d executed (%.1f%%)</li>\n", t.InstructionsCovered, t.Instructions,
+		coveragePercent(t.InstructionsCovered, t.Instructions))
+	fmt.Fprintf(&sb, "<li>Branch directions: %d of %d taken (%.1f%%)</li>\n</ul>\n", t.DirectionsCovered, t.Directions,
+		coveragePercent(t.DirectionsCovered, t.Directions))
+	sb.WriteString("<table>\n<tr><th>Line</th><th>Source</th><th>Coverage</th></tr>\n")
+
+	for _, l := range c.Lines {
+		class := ""
+		note := ""
+		switch l.Status() {
+		case Covered:
+			class = " class=\"covered\""
+			break
+		case PartiallyCovered:
+			class = " class=\"partial\""
+			note = l.describe()
+			break
+		case Uncovered:
+			class = " class=\"uncovered\""
+			note = l.describe()
+			break
+		}
+		fmt.Fprintf(&sb, "<tr%s><td class=\"n\">%d</td><td>%s</td><td>%s</td></tr>\n", class, l.LineNumber,
+			html.EscapeString(l.Contents), note)
+	}
+
+	sb.WriteString("</table>\n</body>\n</html>\n")
+	_, e := io.WriteString(w, sb.String())
+	return e
+}
+
+//Save writes the coverage report to the file as HTML
+func (c *Coverage) Save(fileName, title string) error {
+	f, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the coverage report: %s", e.Error())
+	}
+	defer f.Close()
+
+	if e = c.WriteHTML(f, title); e != nil {
+		return fmt.Errorf("failed to write the coverage report: %s", e.Error())
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package vet
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+)
+
+/**
+ * Execution profile
+ * Counts how many times every instruction was executed over a batch, and joins the counts with the source to show
+ * where the dynamic instruction count goes. The counts of the instructions a line assembles to (such as the words of
+ * a pseudo-instruction) are added together, so the listing has one count per source line.
+ */
+
+//Profile is the number of times every instruction was executed, over a number of samples
+type Profile struct {
+	Counts  map[uint32]uint64 //keyed by the address of the instruction
+	Samples int
+}
+
+//ProfileLine is a line of the annotated listing
+type ProfileLine struct {
+	LineNumber int
+	Contents   string
+	Count      uint64
+	Code       bool //whether the line has instructions, as lines without instructions have no count
+}
+
+//NewProfile creates an empty profile
+func NewProfile() *Profile {
+	return &Profile{Counts: make(map[uint32]uint64)}
+}
+
+//Add adds the counts of one emulation to the profile
+func (p *Profile) Add(counts map[uint32]uint32) {
+	for addr, c := range counts {
+		p.Counts[addr] += uint64(c)
+	}
+	p.Samples++
+}
+
+//Total returns the number of instructions executed over every sample
+func (p *Profile) Total() uint64 {
+	var ret uint64
+	for _, c := range p.Counts {
+		ret += c
+	}
+
+	return ret
+}
+
+//Listing joins the counts with every line of the source
+func (p *Profile) Listing(source string, lineMeta map[uint32]asm.InputLine) []ProfileLine {
+	lines := strings.Split(source, "\n")
+	ret := make([]ProfileLine, len(lines))
+	for i, l := range lines {
+		ret[i] = ProfileLine{
+			LineNumber: i + 1,
+			Contents:   strings.TrimRight(l, "\r"),
+		}
+	}
+
+	for addr, l := range lineMeta {
+		if l.LineNumber < 1 || l.LineNumber > len(ret) {
+			continue
+		}
+		ret[l.LineNumber-1].Code = true
+		ret[l.LineNumber-1].Count += p.Counts[addr]
+	}
+
+	//the nop the assembler inserts after a linking instruction has no line of its own, so it is counted with the link
+	for addr, c := range p.Counts {
+		if _, ok := lineMeta[addr]; ok {
+			continue
+		}
+		if l, ok := lineMeta[addr-4]; ok && l.LineNumber >= 1 && l.LineNumber <= len(ret) {
+			ret[l.LineNumber-1].Count += c
+		}
+	}
+
+	return ret
+}
+
+//the percentage of the total and the average per sample of the count
+func (p *Profile) describe(count, total uint64) (float64, float64) {
+	percent, avg := 0.0, 0.0
+	if total > 0 {
+		percent = float64(count) * 100 / float64(total)
+	}
+	if p.Samples > 0 {
+		avg = float64(count) / float64(p.Samples)
+	}
+
+	return percent, avg
+}
+
+//WriteText writes the annotated listing as text, with the count, percentage of the total and average per sample of
+//every line with instructions
+func (p *Profile) WriteText(w io.Writer, source string, lineMeta map[uint32]asm.InputLine) error {
+	total := p.Total()
+	_, e := fmt.Fprintf(w, "Execution profile of %d sample(s), %d instructions in total.\n%12s %7s %12s %5s  %s\n",
+		p.Samples, total, "Count", "DI %", "Per sample", "Line", "Source")
+	if e != nil {
+		return e
+	}
+
+	for _, l := range p.Listing(source, lineMeta) {
+		if !l.Code {
+			_, e = fmt.Fprintf(w, "%12s %7s %12s %5d  %s\n", "", "", "", l.LineNumber, l.Contents)
+		} else {
+			percent, avg := p.describe(l.Count, total)
+			_, e = fmt.Fprintf(w, "%12d %6.2f%% %12.2f %5d  %s\n", l.Count, percent, avg, l.LineNumber, l.Contents)
+		}
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+//WriteHTML writes the annotated listing as an HTML page, with the lines shaded by how often they are executed
+func (p *Profile) WriteHTML(w io.Writer, title, source string, lineMeta map[uint32]asm.InputLine) error {
+	total := p.Total()
+	listing := p.Listing(source, lineMeta)
+	var max uint64
+	for _, l := range listing {
+		if l.Count > max {
+			max = l.Count
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Execution profile of %s</title>\n", html.EscapeString(title))
+	sb.WriteString("<style>\nbody { font-family: sans-serif; }\ntable { border-collapse: collapse; font-family: monospace; }\n" +
+		"td { padding: 0 8px; white-space: pre; }\ntd.n { text-align: right; color: #555; }\n" +
+		"tr.never td.src { color: #999; }\n</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h2>Execution profile of %s</h2>\n<p>%d sample(s), %d instructions in total.</p>\n",
+		html.EscapeString(title), p.Samples, total)
+	sb.WriteString("<table>\n<tr><th>Count</th><th>DI %</th><th>Per sample</th><th>Line</th><th>Source</th></tr>\n")
+
+	for _, l := range listing {
+		if !l.Code {
+			fmt.Fprintf(&sb, "<tr><td></td><td></td><td></td><td class=\"n\">%d</td><td class=\"src\">%s</td></tr>\n",
+				l.LineNumber, html.EscapeString(l.Contents))
+			continue
+		}
+
+		percent, avg := p.describe(l.Count, total)
+		class := ""
+		style := ""
+		if l.Count == 0 {
+			class = " class=\"never\""
+		} else if max > 0 {
+			//the hotter the line, the redder its background
+			heat := float64(l.Count) / float64(max)
+			style = fmt.Sprintf(" style=\"background: rgba(255, 64, 0, %.2f)\"", 0.1+0.6*heat)
+		}
+		fmt.Fprintf(&sb, "<tr%s%s><td class=\"n\">%d</td><td class=\"n\">%.2f%%</td><td class=\"n\">%.2f</td>"+
+			"<td class=\"n\">%d</td><td class=\"src\">%s</td></tr>\n", class, style, l.Count, percent, avg, l.LineNumber,
+			html.EscapeString(l.Contents))
+	}
+
+	sb.WriteString("</table>\n</body>\n</html>\n")
+	_, e := io.WriteString(w, sb.String())
+	return e
+}
+
+//Save writes the annotated listing to the file, as HTML if the file name ends in .html and as text otherwise
+func (p *Profile) Save(fileName, title, source string, lineMeta map[uint32]asm.InputLine) error {
+	f, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the profile: %s", e.Error())
+	}
+	defer f.Close()
+
+	lower := strings.ToLower(fileName)
+	if strings.HasSuffix(lower, ".html") || strings.HasSuffix(lower, ".htm") {
+		e = p.WriteHTML(f, title, source, lineMeta)
+	} else {
+		e = p.WriteText(f, source, lineMeta)
+	}
+	if e != nil {
+		return fmt.Errorf("failed to write the profile: %s", e.Error())
+	}
+	return nil
+}
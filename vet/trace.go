@@ -0,0 +1,121 @@
+package vet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/danielcbailey/MIPSEmulator/asm"
+	"github.com/danielcbailey/MIPSEmulator/emu"
+)
+
+/**
+ * Instruction trace export
+ * The trace of an emulation (see emu.TraceEntry) joined with the source lines, as text for reading or as JSON lines,
+ * one object per instruction, for scripts.
+ */
+
+//traceRecord is an instruction of the trace as a JSON line
+type traceRecord struct {
+	DI          uint32 `json:"di"`
+	PC          string `json:"pc"`
+	Instruction string `json:"instruction"`
+	Line        int    `json:"line,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Reg         string `json:"reg,omitempty"`
+	RegValue    uint32 `json:"regValue"`
+	Access      string `json:"access,omitempty"`
+	MemAddr     string `json:"memAddr,omitempty"`
+	MemValue    uint32 `json:"memValue"`
+}
+
+//the source line of the instruction, the assembler-inserted nop after a link belongs to the line before it
+func traceLine(pc uint32, lineMeta map[uint32]asm.InputLine) (asm.InputLine, bool) {
+	if l, ok := lineMeta[pc]; ok {
+		return l, true
+	}
+	l, ok := lineMeta[pc-4]
+	return l, ok
+}
+
+//FormatTraceEntry describes the traced instruction on a line, such as
+//"di=12 pc=0x400010 line 5 addi $t0, $t0, -1 | $t0 = 0x4 (4)"
+func FormatTraceEntry(e emu.TraceEntry, lineMeta map[uint32]asm.InputLine) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "di=%d pc=0x%X ", e.DI, e.PC)
+	if l, ok := traceLine(e.PC, lineMeta); ok {
+		fmt.Fprintf(&sb, "line %d ", l.LineNumber)
+	}
+	sb.WriteString(e.Disassemble())
+
+	if e.Reg >= 0 {
+		fmt.Fprintf(&sb, " | %s = 0x%X (%d)", e.RegName(), e.RegValue, int32(e.RegValue))
+	}
+	if e.Access == emu.TraceLoad {
+		fmt.Fprintf(&sb, " | read 0x%X from 0x%X", e.MemValue, e.MemAddr)
+	} else if e.Access == emu.TraceStore {
+		fmt.Fprintf(&sb, " | wrote 0x%X to 0x%X", e.MemValue, e.MemAddr)
+	}
+
+	return sb.String()
+}
+
+//WriteTrace writes the trace as text with the source of every line, or as JSON lines
+func WriteTrace(w io.Writer, trace []emu.TraceEntry, lineMeta map[uint32]asm.InputLine, jsonLines bool) error {
+	enc := json.NewEncoder(w)
+	for _, e := range trace {
+		if !jsonLines {
+			line := FormatTraceEntry(e, lineMeta)
+			if l, ok := traceLine(e.PC, lineMeta); ok {
+				line += fmt.Sprintf(" | \"%s\"", strings.Trim(l.Contents, " \t"))
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r := traceRecord{
+			DI:          e.DI,
+			PC:          fmt.Sprintf("0x%08X", e.PC),
+			Instruction: e.Disassemble(),
+			Reg:         e.RegName(),
+			RegValue:    e.RegValue,
+			MemValue:    e.MemValue,
+		}
+		if l, ok := traceLine(e.PC, lineMeta); ok {
+			r.Line = l.LineNumber
+			r.Source = strings.Trim(l.Contents, " \t")
+		}
+		if e.Access != emu.TraceNoAccess {
+			r.Access = "load"
+			if e.Access == emu.TraceStore {
+				r.Access = "store"
+			}
+			r.MemAddr = fmt.Sprintf("0x%08X", e.MemAddr)
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//SaveTrace writes the trace to the file, as JSON lines if the file name ends in .jsonl or .json and as text otherwise
+func SaveTrace(fileName string, trace []emu.TraceEntry, lineMeta map[uint32]asm.InputLine) error {
+	f, e := os.Create(fileName)
+	if e != nil {
+		return fmt.Errorf("failed to create the trace: %s", e.Error())
+	}
+	defer f.Close()
+
+	lower := strings.ToLower(fileName)
+	jsonLines := strings.HasSuffix(lower, ".jsonl") || strings.HasSuffix(lower, ".json")
+	if e = WriteTrace(f, trace, lineMeta, jsonLines); e != nil {
+		return fmt.Errorf("failed to write the trace: %s", e.Error())
+	}
+	return nil
+}